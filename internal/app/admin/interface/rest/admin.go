@@ -0,0 +1,549 @@
+package rest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/estella-studio/atr-backend/internal/app/admin/usecase"
+	"github.com/estella-studio/atr-backend/internal/domain/dto"
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/estella-studio/atr-backend/internal/middleware"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type AdminHandler struct {
+	Validator    *validator.Validate
+	Middleware   middleware.MiddlewareItf
+	AdminUseCase usecase.AdminUseCaseItf
+}
+
+func NewAdminHandler(
+	routerGroup fiber.Router, validator *validator.Validate,
+	middleware middleware.MiddlewareItf, adminUseCase usecase.AdminUseCaseItf,
+) {
+	adminHandler := AdminHandler{
+		Validator:    validator,
+		Middleware:   middleware,
+		AdminUseCase: adminUseCase,
+	}
+
+	routerGroup = routerGroup.Group(
+		"/admin",
+		middleware.AdminAuthentication,
+		middleware.UserStatus,
+		middleware.EmailVerified,
+		middleware.RequireRole(entity.RoleModerator, entity.RoleAdmin),
+		func(ctx *fiber.Ctx) error {
+			ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+			return ctx.Next()
+		},
+	)
+
+	routerGroup.Get("/reports", adminHandler.ListReports)
+	routerGroup.Get("/reports/:id", adminHandler.GetReport)
+	routerGroup.Patch("/reports/:id", adminHandler.UpdateReport)
+	routerGroup.Get("/users/:id/actions", adminHandler.ListModerationActions)
+	routerGroup.Post("/users/:id/actions", adminHandler.TakeAction)
+	routerGroup.Get("/users/:id/roles", adminHandler.GetRoles)
+	routerGroup.Post("/users/:id/roles", middleware.RequireRole(entity.RoleAdmin), adminHandler.GrantRole)
+	routerGroup.Delete("/users/:id/roles/:role", middleware.RequireRole(entity.RoleAdmin), adminHandler.RevokeRole)
+	routerGroup.Get("/roles/audit", middleware.RequireRole(entity.RoleAdmin), adminHandler.ListRoleAssignments)
+	routerGroup.Get("/appeals", adminHandler.ListAppeals)
+	routerGroup.Patch("/appeals/:id", adminHandler.UpdateAppeal)
+}
+
+func (a *AdminHandler) ListReports(ctx *fiber.Ctx) error {
+	var listReports dto.ListReports
+
+	listReports.Status = ctx.Query("status")
+	listReports.Offset, _ = strconv.Atoi(ctx.Get("X-Offset"))
+	listReports.Limit, _ = strconv.Atoi(ctx.Get("X-Limit"))
+
+	err := a.Validator.Struct(listReports)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid status",
+		)
+	}
+
+	res, err := a.AdminUseCase.ListReports(listReports)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve report list",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved report list",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) GetReport(ctx *fiber.Ctx) error {
+	reportID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	res, err := a.AdminUseCase.GetReport(reportID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"report not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve report",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved report",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) UpdateReport(ctx *fiber.Ctx) error {
+	var updateReport dto.UpdateReport
+
+	moderatorID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&updateReport)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	updateReport.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	updateReport.ModeratorID = moderatorID
+
+	err = a.Validator.Struct(updateReport)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, err := a.AdminUseCase.UpdateReport(updateReport)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"report not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to update report",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "report updated",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) TakeAction(ctx *fiber.Ctx) error {
+	var moderationAction dto.ModerationAction
+
+	moderatorID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&moderationAction)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	moderationAction.UserID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	moderationAction.ModeratorID = moderatorID
+
+	err = a.Validator.Struct(moderationAction)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	if moderationAction.UserID == moderatorID {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"cannot take action against own account",
+		)
+	}
+
+	res, err := a.AdminUseCase.TakeAction(moderationAction)
+	if err != nil {
+		if strings.Contains(err.Error(), "duration required") {
+			return fiber.NewError(
+				http.StatusBadRequest,
+				"duration_hours is required for mute and suspend",
+			)
+		}
+
+		if strings.Contains(err.Error(), "insufficient role") {
+			return fiber.NewError(
+				http.StatusForbidden,
+				"cannot take action against staff of the same or a higher role",
+			)
+		}
+
+		if strings.Contains(err.Error(), "report not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"report not found for this user",
+			)
+		}
+
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"user not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to record moderation action",
+		)
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "moderation action recorded",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) ListModerationActions(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	res, err := a.AdminUseCase.ListModerationActions(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve moderation action list",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved moderation action list",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) GetRoles(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	res, err := a.AdminUseCase.GetRoles(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve user roles",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved user roles",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) GrantRole(ctx *fiber.Ctx) error {
+	var assignRole dto.AssignRole
+
+	actorID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&assignRole)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	assignRole.UserID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	assignRole.ActorID = actorID
+
+	err = a.Validator.Struct(assignRole)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, err := a.AdminUseCase.GrantRole(assignRole)
+	if err != nil {
+		if strings.Contains(err.Error(), "role already assigned") {
+			return fiber.NewError(
+				http.StatusConflict,
+				"role already assigned",
+			)
+		}
+
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"user not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to assign role",
+		)
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "role assigned",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) RevokeRole(ctx *fiber.Ctx) error {
+	var assignRole dto.AssignRole
+
+	actorID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	assignRole.UserID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	assignRole.ActorID = actorID
+	assignRole.Role = ctx.Params("role")
+
+	err = a.Validator.Struct(assignRole)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid role",
+		)
+	}
+
+	res, err := a.AdminUseCase.RevokeRole(assignRole)
+	if err != nil {
+		if strings.Contains(err.Error(), "cannot revoke own admin role") {
+			return fiber.NewError(
+				http.StatusForbidden,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"role not assigned",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to revoke role",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "role revoked",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) ListRoleAssignments(ctx *fiber.Ctx) error {
+	offset, _ := strconv.Atoi(ctx.Get("X-Offset"))
+
+	limit, _ := strconv.Atoi(ctx.Get("X-Limit"))
+
+	res, err := a.AdminUseCase.ListRoleAssignments(offset, limit)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve role assignment list",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved role assignment list",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) ListAppeals(ctx *fiber.Ctx) error {
+	var listAppeals dto.ListAppeals
+
+	listAppeals.Status = ctx.Query("status")
+	listAppeals.Offset, _ = strconv.Atoi(ctx.Get("X-Offset"))
+	listAppeals.Limit, _ = strconv.Atoi(ctx.Get("X-Limit"))
+
+	err := a.Validator.Struct(listAppeals)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid status",
+		)
+	}
+
+	res, err := a.AdminUseCase.ListAppeals(listAppeals)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve appeal list",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved appeal list",
+		"payload": res,
+	})
+}
+
+func (a *AdminHandler) UpdateAppeal(ctx *fiber.Ctx) error {
+	var updateAppeal dto.UpdateAppeal
+
+	moderatorID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&updateAppeal)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	updateAppeal.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	updateAppeal.ModeratorID = moderatorID
+
+	err = a.Validator.Struct(updateAppeal)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, err := a.AdminUseCase.UpdateAppeal(updateAppeal)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"appeal not found",
+			)
+		}
+
+		if strings.Contains(err.Error(), "appeal already resolved") {
+			return fiber.NewError(
+				http.StatusConflict,
+				"appeal already resolved",
+			)
+		}
+
+		if strings.Contains(err.Error(), "insufficient role") {
+			return fiber.NewError(
+				http.StatusForbidden,
+				"cannot reinstate staff of the same or a higher role",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to update appeal",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "appeal updated",
+		"payload": res,
+	})
+}
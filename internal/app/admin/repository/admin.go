@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AdminMySQLItf interface {
+	ListReports(userReporting *[]entity.UserReporting, status string, offset int, limit int) error
+	GetReport(userReporting *entity.UserReporting) error
+	UpdateReport(userReporting *entity.UserReporting) error
+	AddModerationAction(moderationAction *entity.ModerationAction) error
+	ListModerationActions(moderationActions *[]entity.ModerationAction, userID uuid.UUID) error
+	GetRoles(userRoles *[]entity.UserRole, userID uuid.UUID) error
+	GrantRole(userRole *entity.UserRole, roleAssignment *entity.RoleAssignment) error
+	RevokeRole(userRole *entity.UserRole, roleAssignment *entity.RoleAssignment) error
+	ListRoleAssignments(roleAssignments *[]entity.RoleAssignment, offset int, limit int) error
+	GetUserIDFromUsername(user *entity.User) error
+	ListAppeals(appeals *[]entity.Appeal, status string, offset int, limit int) error
+	GetAppeal(appeal *entity.Appeal) error
+	UpdateAppeal(appeal *entity.Appeal, moderationAction *entity.ModerationAction) error
+}
+
+type AdminMySQL struct {
+	db *gorm.DB
+}
+
+func NewAdminMySQL(db *gorm.DB) AdminMySQLItf {
+	return &AdminMySQL{
+		db: db,
+	}
+}
+
+func (r *AdminMySQL) ListReports(userReporting *[]entity.UserReporting, status string, offset int, limit int) error {
+	query := r.db.Debug().
+		Order("created_at desc")
+
+	if status != "" {
+		query = query.
+			Where("status = ?", status)
+	}
+
+	if offset != 0 || limit != 0 {
+		query = query.
+			Limit(limit).
+			Offset(offset)
+	}
+
+	return query.
+		Find(userReporting).
+		Error
+}
+
+func (r *AdminMySQL) GetReport(userReporting *entity.UserReporting) error {
+	return r.db.Debug().
+		First(userReporting, "id = ?", userReporting.ID).
+		Error
+}
+
+func (r *AdminMySQL) UpdateReport(userReporting *entity.UserReporting) error {
+	result := r.db.Debug().
+		Model(&entity.UserReporting{}).
+		Where("id = ?", userReporting.ID).
+		Updates(map[string]any{
+			"status":          userReporting.Status,
+			"moderator_id":    userReporting.ModeratorID,
+			"resolution_note": userReporting.ResolutionNote,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+func (r *AdminMySQL) AddModerationAction(moderationAction *entity.ModerationAction) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		return addModerationAction(tx, moderationAction)
+	})
+}
+
+func addModerationAction(tx *gorm.DB, moderationAction *entity.ModerationAction) error {
+	if moderationAction.ReportID != nil {
+		result := tx.
+			Model(&entity.UserReporting{}).
+			Where("id = ?", *moderationAction.ReportID).
+			Where("user_id = ?", moderationAction.UserID).
+			Updates(map[string]any{
+				"status":       entity.ReportStatusResolved,
+				"moderator_id": moderationAction.ModeratorID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("report not found")
+		}
+	}
+
+	var status string
+
+	switch moderationAction.Action {
+	case entity.ModerationActionSuspend:
+		status = entity.UserStatusSuspended
+	case entity.ModerationActionBan:
+		status = entity.UserStatusBanned
+	case entity.ModerationActionReinstate:
+		status = entity.UserStatusActive
+	}
+
+	if status != "" {
+		result := tx.
+			Model(&entity.User{}).
+			Where("id = ?", moderationAction.UserID).
+			Updates(map[string]any{
+				"status":            status,
+				"status_reason":     moderationAction.Reason,
+				"status_expires_at": moderationAction.ExpiresAt,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("record not found")
+		}
+	}
+
+	return tx.Create(moderationAction).Error
+}
+
+func (r *AdminMySQL) ListModerationActions(moderationActions *[]entity.ModerationAction, userID uuid.UUID) error {
+	return r.db.Debug().
+		Where("user_id = ?", userID).
+		Order("created_at desc").
+		Find(moderationActions).
+		Error
+}
+
+func (r *AdminMySQL) GetRoles(userRoles *[]entity.UserRole, userID uuid.UUID) error {
+	return r.db.Debug().
+		Where("user_id = ?", userID).
+		Find(userRoles).
+		Error
+}
+
+func (r *AdminMySQL) GrantRole(userRole *entity.UserRole, roleAssignment *entity.RoleAssignment) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(userRole).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(roleAssignment).Error
+	})
+}
+
+func (r *AdminMySQL) RevokeRole(userRole *entity.UserRole, roleAssignment *entity.RoleAssignment) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Where("user_id = ?", userRole.UserID).
+			Where("role = ?", userRole.Role).
+			Delete(&entity.UserRole{})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("record not found")
+		}
+
+		return tx.Create(roleAssignment).Error
+	})
+}
+
+func (r *AdminMySQL) ListRoleAssignments(roleAssignments *[]entity.RoleAssignment, offset int, limit int) error {
+	query := r.db.Debug().
+		Order("created_at desc")
+
+	if offset != 0 || limit != 0 {
+		query = query.
+			Limit(limit).
+			Offset(offset)
+	}
+
+	return query.
+		Find(roleAssignments).
+		Error
+}
+
+func (r *AdminMySQL) GetUserIDFromUsername(user *entity.User) error {
+	return r.db.Debug().
+		Select("id").
+		Where("username = ?", user.Username).
+		First(user).
+		Error
+}
+
+func (r *AdminMySQL) ListAppeals(appeals *[]entity.Appeal, status string, offset int, limit int) error {
+	query := r.db.Debug().
+		Order("created_at desc")
+
+	if status != "" {
+		query = query.
+			Where("status = ?", status)
+	}
+
+	if offset != 0 || limit != 0 {
+		query = query.
+			Limit(limit).
+			Offset(offset)
+	}
+
+	return query.
+		Find(appeals).
+		Error
+}
+
+func (r *AdminMySQL) GetAppeal(appeal *entity.Appeal) error {
+	return r.db.Debug().
+		First(appeal, "id = ?", appeal.ID).
+		Error
+}
+
+// UpdateAppeal resolves an open appeal. A given moderation action, such as the
+// reinstatement of an accepted appeal, is recorded in the same transaction.
+func (r *AdminMySQL) UpdateAppeal(appeal *entity.Appeal, moderationAction *entity.ModerationAction) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&entity.Appeal{}).
+			Where("id = ?", appeal.ID).
+			Where("status = ?", entity.AppealStatusOpen).
+			Updates(map[string]any{
+				"status":        appeal.Status,
+				"moderator_id":  appeal.ModeratorID,
+				"response_note": appeal.ResponseNote,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("appeal already resolved")
+		}
+
+		if moderationAction == nil {
+			return nil
+		}
+
+		return addModerationAction(tx, moderationAction)
+	})
+}
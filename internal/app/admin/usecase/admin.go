@@ -0,0 +1,335 @@
+package usecase
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/estella-studio/atr-backend/internal/app/admin/repository"
+	"github.com/estella-studio/atr-backend/internal/domain/dto"
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/google/uuid"
+)
+
+type AdminUseCaseItf interface {
+	ListReports(listReports dto.ListReports) (*[]dto.ResponseReport, error)
+	GetReport(reportID uuid.UUID) (dto.ResponseReport, error)
+	UpdateReport(updateReport dto.UpdateReport) (dto.ResponseReport, error)
+	TakeAction(moderationAction dto.ModerationAction) (dto.ResponseModerationAction, error)
+	ListModerationActions(userID uuid.UUID) (*[]dto.ResponseModerationAction, error)
+	GetRoles(userID uuid.UUID) (dto.ResponseRoles, error)
+	GrantRole(assignRole dto.AssignRole) (dto.ResponseRoles, error)
+	RevokeRole(assignRole dto.AssignRole) (dto.ResponseRoles, error)
+	ListRoleAssignments(offset int, limit int) (*[]dto.ResponseRoleAssignment, error)
+	BootstrapAdmin(username string) error
+	ListAppeals(listAppeals dto.ListAppeals) (*[]dto.ResponseAppeal, error)
+	UpdateAppeal(updateAppeal dto.UpdateAppeal) (dto.ResponseAppeal, error)
+}
+
+type AdminUseCase struct {
+	adminRepo repository.AdminMySQLItf
+}
+
+func NewAdminUseCase(adminRepo repository.AdminMySQLItf) AdminUseCaseItf {
+	return &AdminUseCase{
+		adminRepo: adminRepo,
+	}
+}
+
+func (a *AdminUseCase) ListReports(listReports dto.ListReports) (*[]dto.ResponseReport, error) {
+	userReporting := new([]entity.UserReporting)
+
+	err := a.adminRepo.ListReports(userReporting, listReports.Status, listReports.Offset, listReports.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseReport, len(*userReporting))
+
+	for i, report := range *userReporting {
+		res[i] = report.ParseToDTOResponseReport()
+	}
+
+	return &res, nil
+}
+
+func (a *AdminUseCase) GetReport(reportID uuid.UUID) (dto.ResponseReport, error) {
+	userReporting := entity.UserReporting{
+		ID: reportID,
+	}
+
+	err := a.adminRepo.GetReport(&userReporting)
+	if err != nil {
+		return dto.ResponseReport{}, err
+	}
+
+	return userReporting.ParseToDTOResponseReport(), nil
+}
+
+func (a *AdminUseCase) UpdateReport(updateReport dto.UpdateReport) (dto.ResponseReport, error) {
+	userReporting := entity.UserReporting{
+		ID:             updateReport.ID,
+		Status:         updateReport.Status,
+		ModeratorID:    &updateReport.ModeratorID,
+		ResolutionNote: updateReport.Note,
+	}
+
+	err := a.adminRepo.UpdateReport(&userReporting)
+	if err != nil {
+		return dto.ResponseReport{}, err
+	}
+
+	return a.GetReport(updateReport.ID)
+}
+
+func (a *AdminUseCase) TakeAction(moderationAction dto.ModerationAction) (dto.ResponseModerationAction, error) {
+	action := entity.ModerationAction{
+		ID:          uuid.New(),
+		UserID:      moderationAction.UserID,
+		ModeratorID: moderationAction.ModeratorID,
+		ReportID:    moderationAction.ReportID,
+		Action:      moderationAction.Action,
+		Reason:      moderationAction.Reason,
+	}
+
+	switch moderationAction.Action {
+	case entity.ModerationActionMute, entity.ModerationActionSuspend:
+		if moderationAction.DurationHours == 0 {
+			return dto.ResponseModerationAction{}, errors.New("duration required")
+		}
+
+		expiresAt := time.Now().Add(time.Hour * time.Duration(moderationAction.DurationHours))
+		action.ExpiresAt = &expiresAt
+	}
+
+	err := a.checkOutranks(moderationAction.ModeratorID, moderationAction.UserID)
+	if err != nil {
+		return dto.ResponseModerationAction{}, err
+	}
+
+	err = a.adminRepo.AddModerationAction(&action)
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key constraint fails") {
+			return dto.ResponseModerationAction{}, errors.New("record not found")
+		}
+
+		return dto.ResponseModerationAction{}, err
+	}
+
+	return action.ParseToDTOResponseModerationAction(), nil
+}
+
+func (a *AdminUseCase) checkOutranks(moderatorID uuid.UUID, userID uuid.UUID) error {
+	moderatorRoles := new([]entity.UserRole)
+
+	err := a.adminRepo.GetRoles(moderatorRoles, moderatorID)
+	if err != nil {
+		return err
+	}
+
+	userRoles := new([]entity.UserRole)
+
+	err = a.adminRepo.GetRoles(userRoles, userID)
+	if err != nil {
+		return err
+	}
+
+	if entity.RoleRank(*moderatorRoles) <= entity.RoleRank(*userRoles) {
+		return errors.New("insufficient role")
+	}
+
+	return nil
+}
+
+func (a *AdminUseCase) ListModerationActions(userID uuid.UUID) (*[]dto.ResponseModerationAction, error) {
+	moderationActions := new([]entity.ModerationAction)
+
+	err := a.adminRepo.ListModerationActions(moderationActions, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseModerationAction, len(*moderationActions))
+
+	for i, moderationAction := range *moderationActions {
+		res[i] = moderationAction.ParseToDTOResponseModerationAction()
+	}
+
+	return &res, nil
+}
+
+func (a *AdminUseCase) GetRoles(userID uuid.UUID) (dto.ResponseRoles, error) {
+	userRoles := new([]entity.UserRole)
+
+	err := a.adminRepo.GetRoles(userRoles, userID)
+	if err != nil {
+		return dto.ResponseRoles{}, err
+	}
+
+	res := dto.ResponseRoles{
+		UserID: userID,
+		Roles:  []string{entity.RolePlayer},
+	}
+
+	for _, userRole := range *userRoles {
+		res.Roles = append(res.Roles, userRole.Role)
+	}
+
+	return res, nil
+}
+
+func (a *AdminUseCase) GrantRole(assignRole dto.AssignRole) (dto.ResponseRoles, error) {
+	userRole := entity.UserRole{
+		UserID: assignRole.UserID,
+		Role:   assignRole.Role,
+	}
+
+	roleAssignment := entity.RoleAssignment{
+		ID:      uuid.New(),
+		UserID:  assignRole.UserID,
+		ActorID: assignRole.ActorID,
+		Role:    assignRole.Role,
+		Action:  entity.RoleActionGrant,
+	}
+
+	err := a.adminRepo.GrantRole(&userRole, &roleAssignment)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return dto.ResponseRoles{}, errors.New("role already assigned")
+		}
+
+		if strings.Contains(err.Error(), "foreign key constraint fails") {
+			return dto.ResponseRoles{}, errors.New("record not found")
+		}
+
+		return dto.ResponseRoles{}, err
+	}
+
+	return a.GetRoles(assignRole.UserID)
+}
+
+func (a *AdminUseCase) RevokeRole(assignRole dto.AssignRole) (dto.ResponseRoles, error) {
+	if assignRole.UserID == assignRole.ActorID && assignRole.Role == entity.RoleAdmin {
+		return dto.ResponseRoles{}, errors.New("cannot revoke own admin role")
+	}
+
+	userRole := entity.UserRole{
+		UserID: assignRole.UserID,
+		Role:   assignRole.Role,
+	}
+
+	roleAssignment := entity.RoleAssignment{
+		ID:      uuid.New(),
+		UserID:  assignRole.UserID,
+		ActorID: assignRole.ActorID,
+		Role:    assignRole.Role,
+		Action:  entity.RoleActionRevoke,
+	}
+
+	err := a.adminRepo.RevokeRole(&userRole, &roleAssignment)
+	if err != nil {
+		return dto.ResponseRoles{}, err
+	}
+
+	return a.GetRoles(assignRole.UserID)
+}
+
+func (a *AdminUseCase) ListRoleAssignments(offset int, limit int) (*[]dto.ResponseRoleAssignment, error) {
+	roleAssignments := new([]entity.RoleAssignment)
+
+	err := a.adminRepo.ListRoleAssignments(roleAssignments, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseRoleAssignment, len(*roleAssignments))
+
+	for i, roleAssignment := range *roleAssignments {
+		res[i] = roleAssignment.ParseToDTOResponseRoleAssignment()
+	}
+
+	return &res, nil
+}
+
+func (a *AdminUseCase) BootstrapAdmin(username string) error {
+	user := entity.User{
+		Username: username,
+	}
+
+	err := a.adminRepo.GetUserIDFromUsername(&user)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.GrantRole(dto.AssignRole{
+		UserID:  user.ID,
+		ActorID: uuid.Nil,
+		Role:    entity.RoleAdmin,
+	})
+	if err != nil && strings.Contains(err.Error(), "role already assigned") {
+		return nil
+	}
+
+	return err
+}
+
+func (a *AdminUseCase) ListAppeals(listAppeals dto.ListAppeals) (*[]dto.ResponseAppeal, error) {
+	appeals := new([]entity.Appeal)
+
+	err := a.adminRepo.ListAppeals(appeals, listAppeals.Status, listAppeals.Offset, listAppeals.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseAppeal, len(*appeals))
+
+	for i, appeal := range *appeals {
+		res[i] = appeal.ParseToDTOResponseAppeal()
+	}
+
+	return &res, nil
+}
+
+func (a *AdminUseCase) UpdateAppeal(updateAppeal dto.UpdateAppeal) (dto.ResponseAppeal, error) {
+	appeal := entity.Appeal{
+		ID: updateAppeal.ID,
+	}
+
+	err := a.adminRepo.GetAppeal(&appeal)
+	if err != nil {
+		return dto.ResponseAppeal{}, err
+	}
+
+	appeal.Status = updateAppeal.Status
+	appeal.ModeratorID = &updateAppeal.ModeratorID
+	appeal.ResponseNote = updateAppeal.Note
+
+	var reinstate *entity.ModerationAction
+
+	if appeal.Status == entity.AppealStatusAccepted {
+		err = a.checkOutranks(updateAppeal.ModeratorID, appeal.UserID)
+		if err != nil {
+			return dto.ResponseAppeal{}, err
+		}
+
+		reinstate = &entity.ModerationAction{
+			ID:          uuid.New(),
+			UserID:      appeal.UserID,
+			ModeratorID: updateAppeal.ModeratorID,
+			Action:      entity.ModerationActionReinstate,
+			Reason:      updateAppeal.Note,
+		}
+	}
+
+	err = a.adminRepo.UpdateAppeal(&appeal, reinstate)
+	if err != nil {
+		return dto.ResponseAppeal{}, err
+	}
+
+	err = a.adminRepo.GetAppeal(&appeal)
+	if err != nil {
+		return dto.ResponseAppeal{}, err
+	}
+
+	return appeal.ParseToDTOResponseAppeal(), nil
+}
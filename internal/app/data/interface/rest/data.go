@@ -1,17 +1,22 @@
 package rest
 
 import (
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	datausecase "github.com/estella-studio/atr-backend/internal/app/data/usecase"
 	userusecase "github.com/estella-studio/atr-backend/internal/app/user/usecase"
 	"github.com/estella-studio/atr-backend/internal/domain/dto"
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
 	"github.com/estella-studio/atr-backend/internal/infra/env"
 	"github.com/estella-studio/atr-backend/internal/infra/s3"
 	"github.com/estella-studio/atr-backend/internal/middleware"
@@ -33,7 +38,7 @@ func NewDataHandler(
 	routerGroup fiber.Router, validator *validator.Validate,
 	middleware middleware.MiddlewareItf, dataUseCase datausecase.DataUseCaseItf,
 	userUseCase userusecase.UserUseCaseItf, env *env.Env,
-	s3 s3.S3Itf,
+	s3Config s3.S3Itf,
 ) {
 	dataHandler := DataHandler{
 		Validator:   validator,
@@ -41,15 +46,31 @@ func NewDataHandler(
 		DataUseCase: dataUseCase,
 		UserUseCase: userUseCase,
 		Env:         env,
-		S3:          s3,
+		S3:          s3Config,
 	}
 
 	routerGroup = routerGroup.Group("/data")
 
-	routerGroup.Post("/add", middleware.Authentication, middleware.UserStatus, dataHandler.Add)
-	routerGroup.Get("/get", middleware.Authentication, middleware.UserStatus, dataHandler.Retrieve)
-	routerGroup.Get("/list", middleware.Authentication, middleware.UserStatus, dataHandler.List)
-	routerGroup.Get("/listpublic", middleware.Authentication, middleware.UserStatus, dataHandler.ListPublic)
+	if _, ok := s3Config.(s3.PresignVerifierItf); ok {
+		routerGroup.Put("/local/+", dataHandler.LocalUpload)
+		routerGroup.Get("/local/+", dataHandler.LocalDownload)
+	}
+
+	routerGroup.Post("/add", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Add)
+	routerGroup.Get("/get", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Retrieve)
+	routerGroup.Post("/upload-url", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.UploadURL)
+	routerGroup.Post("/:id/confirm", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Confirm)
+	routerGroup.Put("/:id", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Overwrite)
+	routerGroup.Delete("/:id", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Delete)
+	routerGroup.Get("/:id/status", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Status)
+	routerGroup.Get("/:id/download", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Download)
+	routerGroup.Get("/:id/download-url", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.DownloadURL)
+	routerGroup.Get("/slots/:slot/revisions", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.ListRevisions)
+	routerGroup.Post("/slots/:slot/revisions/:revision/restore", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Restore)
+	routerGroup.Post("/slots/:slot/prune", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Prune)
+	routerGroup.Get("/quota", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.Quota)
+	routerGroup.Get("/list", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.List)
+	routerGroup.Get("/listpublic", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, dataHandler.ListPublic)
 }
 
 func (d *DataHandler) Add(ctx *fiber.Ctx) error {
@@ -71,6 +92,11 @@ func (d *DataHandler) Add(ctx *fiber.Ctx) error {
 		)
 	}
 
+	add.Slot = ctx.Get("X-Slot")
+	add.IfMatch = ctx.Get(fiber.HeaderIfMatch)
+	add.Checksum = ctx.Get("X-Checksum")
+	add.GameVersion = ctx.Get("X-Game-Version")
+
 	err = d.Validator.Struct(add)
 	if err != nil {
 		return fiber.NewError(
@@ -100,26 +126,215 @@ func (d *DataHandler) Add(ctx *fiber.Ctx) error {
 		)
 	}
 
+	add.ContentType = file.Header.Get(fiber.HeaderContentType)
+	if add.ContentType == "" {
+		add.ContentType = fiber.MIMEOctetStream
+	}
+
 	add.ID = uuid.New()
 	add.UserID = userID
 	add.Data = fmt.Sprintf("%s/%v", d.Env.S3BucketURLPrefix, add.ID)
 
-	res, err := d.DataUseCase.Add(add)
+	res, err := d.DataUseCase.Add(add, byteContainer)
 	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			return d.quotaExceeded(ctx, userID)
+		}
+
+		if strings.Contains(err.Error(), "checksum mismatch") {
+			return fiber.NewError(
+				http.StatusUnprocessableEntity,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "data was modified") {
+			return ctx.Status(http.StatusConflict).JSON(fiber.Map{
+				"message": "save data was modified",
+				"payload": res,
+			})
+		}
+
+		if strings.Contains(err.Error(), "failed to upload data") {
+			return ctx.Status(http.StatusBadGateway).JSON(fiber.Map{
+				"message": "failed to upload data",
+				"payload": res,
+			})
+		}
+
 		return fiber.NewError(
 			http.StatusInternalServerError,
 			"failed to save data",
 		)
 	}
 
-	go func() {
-		err = d.S3.Upload(context.Background(), add.ID.String(), byteContainer)
-		if err != nil {
-			log.Println(err)
+	ctx.Set(fiber.HeaderETag, res.ETag)
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "data saved",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) UploadURL(ctx *fiber.Ctx) error {
+	var add dto.Add
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	add.Type, err = strconv.ParseBool(ctx.Get("X-Type"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	add.Slot = ctx.Get("X-Slot")
+	add.IfMatch = ctx.Get(fiber.HeaderIfMatch)
+	add.Checksum = ctx.Get("X-Checksum")
+	add.ContentType = ctx.Get("X-Content-Type")
+	add.GameVersion = ctx.Get("X-Game-Version")
+
+	add.Size, err = strconv.ParseInt(ctx.Get("X-Size"), 10, 64)
+	if err != nil || add.Size <= 0 || add.Checksum == "" {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	err = d.Validator.StructExcept(add, "Type")
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	if add.Size > int64(d.Env.BodyLimit)*1024*1024 {
+		return fiber.NewError(
+			http.StatusRequestEntityTooLarge,
+			"save data is too large",
+		)
+	}
+
+	add.ID = uuid.New()
+	add.UserID = userID
+	add.Data = fmt.Sprintf("%s/%v", d.Env.S3BucketURLPrefix, add.ID)
+
+	expires := time.Duration(d.Env.S3PresignExpiryMinutes) * time.Minute
+
+	url, err := d.S3.PresignUpload(context.Background(), add.ID.String(), add.Size, add.Checksum, expires)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to create upload url",
+		)
+	}
+
+	res, err := d.DataUseCase.AddPending(add)
+	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			return d.quotaExceeded(ctx, userID)
+		}
+
+		if strings.Contains(err.Error(), "data was modified") {
+			return ctx.Status(http.StatusConflict).JSON(fiber.Map{
+				"message": "save data was modified",
+				"payload": res,
+			})
 		}
-	}()
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to save data",
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, res.ETag)
 
 	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "upload url created",
+		"payload": dto.ResponsePresign{
+			ID:        add.ID,
+			Method:    http.MethodPut,
+			URL:       url,
+			ExpiresAt: time.Now().Add(expires),
+		},
+	})
+}
+
+func (d *DataHandler) Confirm(ctx *fiber.Ctx) error {
+	var confirm dto.Confirm
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	confirm.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	confirm.UserID = userID
+
+	res, err := d.DataUseCase.Confirm(confirm)
+	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			return d.quotaExceeded(ctx, userID)
+		}
+
+		if strings.Contains(err.Error(), "data already confirmed") {
+			return fiber.NewError(
+				http.StatusConflict,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "data not uploaded") {
+			return fiber.NewError(
+				http.StatusConflict,
+				"save data has not been uploaded",
+			)
+		}
+
+		if strings.Contains(err.Error(), "checksum mismatch") {
+			return ctx.Status(http.StatusUnprocessableEntity).JSON(fiber.Map{
+				"message": "checksum mismatch",
+				"payload": res,
+			})
+		}
+
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to confirm save data",
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, res.ETag)
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "data saved",
 		"payload": res,
 	})
@@ -169,14 +384,17 @@ func (d *DataHandler) Retrieve(ctx *fiber.Ctx) error {
 		)
 	}
 
+	ctx.Set(fiber.HeaderETag, res.ETag)
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "retrieved save data",
 		"payload": res,
 	})
 }
 
-func (d *DataHandler) List(ctx *fiber.Ctx) error {
-	var res *[]dto.ResponseList
+func (d *DataHandler) Status(ctx *fiber.Ctx) error {
+	var download dto.Download
 
 	userID, err := uuid.Parse(ctx.Locals("userID").(string))
 	if err != nil {
@@ -186,61 +404,818 @@ func (d *DataHandler) List(ctx *fiber.Ctx) error {
 		)
 	}
 
-	offset, _ := strconv.Atoi(ctx.Get("X-Offset"))
+	download.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
 
-	limit, _ := strconv.Atoi(ctx.Get("X-Limit"))
+	download.UserID = userID
 
-	res, err = d.DataUseCase.List(userID, offset, limit)
+	res, err := d.DataUseCase.Download(download)
 	if err != nil {
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
 		return fiber.NewError(
 			http.StatusInternalServerError,
-			"failed to retrieve save data list",
+			"failed to retrieve save data",
 		)
 	}
 
-	if len(*res) == 0 {
-		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{
-			"message": "no save data found",
-		})
-	}
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
 
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
-		"message": "retrieved save data list",
-		"payload": res,
+		"message": "retrieved save data status",
+		"payload": dto.ResponseStatus{
+			ID:     res.ID,
+			Status: res.Status,
+		},
 	})
 }
 
-func (d *DataHandler) ListPublic(ctx *fiber.Ctx) error {
-	var res *[]dto.ResponseList
+func (d *DataHandler) Download(ctx *fiber.Ctx) error {
+	var download dto.Download
 
-	userID, err := d.UserUseCase.GetUserIDFromUsername(ctx.Get("X-Username"))
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	download.ID, err = uuid.Parse(ctx.Params("id"))
 	if err != nil {
 		return fiber.NewError(
 			http.StatusBadRequest,
-			"invalid username",
+			"invalid id",
 		)
 	}
 
-	offset, _ := strconv.Atoi(ctx.Get("X-Offset"))
+	download.UserID = userID
 
-	limit, _ := strconv.Atoi(ctx.Get("X-Limit"))
+	byteRange := ctx.Get(fiber.HeaderRange)
+	if strings.Contains(byteRange, ",") {
+		return fiber.NewError(
+			http.StatusRequestedRangeNotSatisfiable,
+			"multiple ranges are not supported",
+		)
+	}
 
-	res, err = d.DataUseCase.ListPublic(userID, offset, limit)
+	res, err := d.DataUseCase.Download(download)
 	if err != nil {
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
 		return fiber.NewError(
 			http.StatusInternalServerError,
-			"failed to retrieve save data list",
+			"failed to retrieve save data",
 		)
 	}
 
-	if len(*res) == 0 {
-		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{
-			"message": "no save data found",
-		})
+	if res.Status != entity.DataStatusStored {
+		return fiber.NewError(
+			http.StatusConflict,
+			"save data is not ready",
+		)
 	}
 
-	return ctx.Status(http.StatusOK).JSON(fiber.Map{
-		"message": "retrieved save data list",
+	objectRange := byteRange
+	if res.Compressed {
+		objectRange = ""
+	}
+
+	object, err := d.S3.Download(context.Background(), res.ObjectKey, objectRange)
+	if err != nil {
+		if errors.Is(err, s3.ErrInvalidRange) {
+			return fiber.NewError(
+				http.StatusRequestedRangeNotSatisfiable,
+				"invalid range",
+			)
+		}
+
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to download save data",
+		)
+	}
+
+	if res.Compressed {
+		object, err = decompress(object, byteRange, res.Size)
+		if err != nil {
+			if errors.Is(err, s3.ErrInvalidRange) {
+				return fiber.NewError(
+					http.StatusRequestedRangeNotSatisfiable,
+					"invalid range",
+				)
+			}
+
+			return fiber.NewError(
+				http.StatusInternalServerError,
+				"failed to download save data",
+			)
+		}
+	}
+
+	if res.ContentType != "" {
+		object.ContentType = res.ContentType
+	}
+
+	ctx.Set(fiber.HeaderAcceptRanges, "bytes")
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%v"`, res.ID))
+
+	if object.ContentType != "" {
+		ctx.Set(fiber.HeaderContentType, object.ContentType)
+	} else {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+	}
+
+	status := http.StatusOK
+	if object.ContentRange != "" {
+		ctx.Set(fiber.HeaderContentRange, object.ContentRange)
+		status = http.StatusPartialContent
+	}
+
+	return ctx.Status(status).SendStream(object.Body, int(object.ContentLength))
+}
+
+type gzipBody struct {
+	io.Reader
+	gzip   *gzip.Reader
+	object io.Closer
+}
+
+func (g *gzipBody) Close() error {
+	g.gzip.Close()
+
+	return g.object.Close()
+}
+
+func decompress(object *s3.Object, byteRange string, size int64) (*s3.Object, error) {
+	reader, err := gzip.NewReader(object.Body)
+	if err != nil {
+		object.Body.Close()
+
+		return nil, err
+	}
+
+	body := &gzipBody{
+		Reader: reader,
+		gzip:   reader,
+		object: object.Body,
+	}
+
+	res := &s3.Object{
+		Body:          body,
+		ContentLength: size,
+		ContentType:   object.ContentType,
+	}
+
+	if byteRange == "" {
+		return res, nil
+	}
+
+	start, end, err := s3.ParseRange(byteRange, size)
+	if err != nil {
+		body.Close()
+
+		return nil, err
+	}
+
+	_, err = io.CopyN(io.Discard, reader, start)
+	if err != nil {
+		body.Close()
+
+		return nil, err
+	}
+
+	body.Reader = io.LimitReader(reader, end-start+1)
+	res.ContentLength = end - start + 1
+	res.ContentRange = fmt.Sprintf("bytes %d-%d/%d", start, end, size)
+
+	return res, nil
+}
+
+func (d *DataHandler) LocalUpload(ctx *fiber.Ctx) error {
+	objectKey := ctx.Params("+")
+
+	presigned, err := d.S3.(s3.PresignVerifierItf).VerifyPresigned(http.MethodPut, objectKey, ctx.Queries())
+	if err != nil {
+		return fiber.NewError(
+			http.StatusForbidden,
+			"invalid or expired url",
+		)
+	}
+
+	object := ctx.Body()
+
+	if int64(len(object)) != presigned.Size {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"content length does not match the signed size",
+		)
+	}
+
+	checksum := sha256.Sum256(object)
+	if presigned.Checksum != "" && !strings.EqualFold(presigned.Checksum, hex.EncodeToString(checksum[:])) {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"checksum does not match the signed checksum",
+		)
+	}
+
+	contentType := ctx.Get(fiber.HeaderContentType)
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+
+	err = d.S3.Upload(context.Background(), objectKey, object, contentType, "")
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to upload object",
+		)
+	}
+
+	return ctx.SendStatus(http.StatusOK)
+}
+
+func (d *DataHandler) LocalDownload(ctx *fiber.Ctx) error {
+	objectKey := ctx.Params("+")
+
+	presigned, err := d.S3.(s3.PresignVerifierItf).VerifyPresigned(http.MethodGet, objectKey, ctx.Queries())
+	if err != nil {
+		return fiber.NewError(
+			http.StatusForbidden,
+			"invalid or expired url",
+		)
+	}
+
+	object, err := d.S3.Download(context.Background(), objectKey, ctx.Get(fiber.HeaderRange))
+	if err != nil {
+		if errors.Is(err, s3.ErrInvalidRange) {
+			return fiber.NewError(
+				http.StatusRequestedRangeNotSatisfiable,
+				"invalid range",
+			)
+		}
+
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"object not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to download object",
+		)
+	}
+
+	ctx.Set(fiber.HeaderAcceptRanges, "bytes")
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+	ctx.Set(fiber.HeaderContentType, fiber.MIMEOctetStream)
+
+	if presigned.ContentEncoding != "" {
+		ctx.Set(fiber.HeaderContentEncoding, presigned.ContentEncoding)
+	}
+
+	status := http.StatusOK
+	if object.ContentRange != "" {
+		ctx.Set(fiber.HeaderContentRange, object.ContentRange)
+		status = http.StatusPartialContent
+	}
+
+	return ctx.Status(status).SendStream(object.Body, int(object.ContentLength))
+}
+
+func (d *DataHandler) DownloadURL(ctx *fiber.Ctx) error {
+	var download dto.Download
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	download.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	download.UserID = userID
+
+	res, err := d.DataUseCase.Download(download)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve save data",
+		)
+	}
+
+	if res.Status != entity.DataStatusStored {
+		return fiber.NewError(
+			http.StatusConflict,
+			"save data is not ready",
+		)
+	}
+
+	expires := time.Duration(d.Env.S3PresignExpiryMinutes) * time.Minute
+
+	contentEncoding := ""
+	if res.Compressed {
+		contentEncoding = "gzip"
+	}
+
+	url, err := d.S3.PresignDownload(context.Background(), res.ObjectKey, contentEncoding, expires)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to create download url",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "download url created",
+		"payload": dto.ResponsePresign{
+			ID:        res.ID,
+			Method:    http.MethodGet,
+			URL:       url,
+			ExpiresAt: time.Now().Add(expires),
+		},
+	})
+}
+
+func (d *DataHandler) Overwrite(ctx *fiber.Ctx) error {
+	var overwrite dto.Overwrite
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	overwrite.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	overwrite.Type, err = strconv.ParseBool(ctx.Get("X-Type"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	overwrite.UserID = userID
+	overwrite.IfMatch = ctx.Get(fiber.HeaderIfMatch)
+	overwrite.Checksum = ctx.Get("X-Checksum")
+	overwrite.GameVersion = ctx.Get("X-Game-Version")
+
+	err = d.Validator.Struct(overwrite)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	file, err := ctx.FormFile("file")
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"cannot get data",
+		)
+	}
+
+	fileContent, err := file.Open()
+	if err != nil {
+		return fiber.NewError(http.StatusInternalServerError, "failed to open file")
+	}
+
+	byteContainer, err := io.ReadAll(fileContent)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to read file",
+		)
+	}
+
+	overwrite.ContentType = file.Header.Get(fiber.HeaderContentType)
+	if overwrite.ContentType == "" {
+		overwrite.ContentType = fiber.MIMEOctetStream
+	}
+
+	res, err := d.DataUseCase.Overwrite(overwrite, byteContainer)
+	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			return d.quotaExceeded(ctx, userID)
+		}
+
+		if strings.Contains(err.Error(), "checksum mismatch") {
+			return fiber.NewError(
+				http.StatusUnprocessableEntity,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "data was modified") {
+			return ctx.Status(http.StatusConflict).JSON(fiber.Map{
+				"message": "save data was modified",
+				"payload": res,
+			})
+		}
+
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
+		if strings.Contains(err.Error(), "data upload in progress") {
+			return fiber.NewError(
+				http.StatusConflict,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "failed to upload data") {
+			return fiber.NewError(
+				http.StatusBadGateway,
+				err.Error(),
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to save data",
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, res.ETag)
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "data overwritten",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) Delete(ctx *fiber.Ctx) error {
+	var deleteData dto.Delete
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	deleteData.ID, err = uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	deleteData.UserID = userID
+
+	err = d.DataUseCase.Delete(deleteData)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") ||
+			strings.Contains(err.Error(), "data not accessible") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current id",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to delete save data",
+		)
+	}
+
+	return ctx.Status(http.StatusNoContent).Context().Err()
+}
+
+func (d *DataHandler) List(ctx *fiber.Ctx) error {
+	var res *[]dto.ResponseList
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	offset, _ := strconv.Atoi(ctx.Get("X-Offset"))
+
+	limit, _ := strconv.Atoi(ctx.Get("X-Limit"))
+
+	res, err = d.DataUseCase.List(userID, offset, limit)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve save data list",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	if len(*res) == 0 {
+		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "no save data found",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved save data list",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) ListPublic(ctx *fiber.Ctx) error {
+	var res *[]dto.ResponseList
+
+	userID, err := d.UserUseCase.GetUserIDFromUsername(ctx.Get("X-Username"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid username",
+		)
+	}
+
+	offset, _ := strconv.Atoi(ctx.Get("X-Offset"))
+
+	limit, _ := strconv.Atoi(ctx.Get("X-Limit"))
+
+	res, err = d.DataUseCase.ListPublic(userID, offset, limit)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve save data list",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	if len(*res) == 0 {
+		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "no save data found",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved save data list",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) ListRevisions(ctx *fiber.Ctx) error {
+	var slot dto.Slot
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	slot.UserID = userID
+	slot.Slot = ctx.Params("slot")
+
+	err = d.Validator.Struct(slot)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid slot",
+		)
+	}
+
+	res, err := d.DataUseCase.ListRevisions(slot)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve save data revisions",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	if len(*res) == 0 {
+		return ctx.Status(http.StatusNotFound).JSON(fiber.Map{
+			"message": "no save data found",
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved save data revisions",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) Restore(ctx *fiber.Ctx) error {
+	var restore dto.Restore
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	revision, err := strconv.ParseUint(ctx.Params("revision"), 10, 32)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid revision",
+		)
+	}
+
+	restore.UserID = userID
+	restore.Slot = ctx.Params("slot")
+	restore.Revision = uint(revision)
+	restore.IfMatch = ctx.Get(fiber.HeaderIfMatch)
+
+	err = d.Validator.Struct(restore)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, err := d.DataUseCase.Restore(restore)
+	if err != nil {
+		if strings.Contains(err.Error(), "quota exceeded") {
+			return d.quotaExceeded(ctx, userID)
+		}
+
+		if strings.Contains(err.Error(), "data was modified") {
+			return ctx.Status(http.StatusConflict).JSON(fiber.Map{
+				"message": "save data was modified",
+				"payload": res,
+			})
+		}
+
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"no save data found with current revision",
+			)
+		}
+
+		if strings.Contains(err.Error(), "data is not ready") {
+			return fiber.NewError(
+				http.StatusConflict,
+				"save data is not ready",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to restore save data",
+		)
+	}
+
+	ctx.Set(fiber.HeaderETag, res.ETag)
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "save data restored",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) Prune(ctx *fiber.Ctx) error {
+	var prune dto.Prune
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	prune.Keep, err = strconv.Atoi(ctx.Get("X-Keep"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	prune.UserID = userID
+	prune.Slot = ctx.Params("slot")
+
+	err = d.Validator.Struct(prune)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, err := d.DataUseCase.Prune(prune)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to prune save data revisions",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "save data revisions pruned",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) Quota(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	res, err := d.DataUseCase.Quota(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve quota",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved quota",
+		"payload": res,
+	})
+}
+
+func (d *DataHandler) quotaExceeded(ctx *fiber.Ctx, userID uuid.UUID) error {
+	res, err := d.DataUseCase.Quota(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInsufficientStorage,
+			"quota exceeded",
+		)
+	}
+
+	return ctx.Status(http.StatusInsufficientStorage).JSON(fiber.Map{
+		"message": "quota exceeded",
 		"payload": res,
 	})
 }
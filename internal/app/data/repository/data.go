@@ -1,14 +1,33 @@
 package repository
 
 import (
+	"errors"
+	"time"
+
 	"github.com/estella-studio/atr-backend/internal/domain/dto"
 	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const dataColumns = "id, user_id, slot, revision, object_key, compressed, version, size, checksum, content_type, game_version, type, status, created_at"
+
 type DataMySQLItf interface {
 	Add(data *entity.Data) error
+	AddWithinQuota(data *entity.Data, limit dto.ResponseQuota, blob *entity.Blob) error
 	Retrieve(data *entity.Data, userParam dto.Retrieve) error
+	GetAccess(data *entity.Data) error
+	ConfirmUpload(data *entity.Data, blob *entity.Blob) error
+	ListStale(data *[]entity.Data, status string, before time.Time) error
+	Update(data *entity.Data) error
+	UpdateWithinQuota(data *entity.Data, limit dto.ResponseQuota, addedBytes int64, blob *entity.Blob, deleteObject func(objectKey string) error) error
+	Delete(data *entity.Data, deleteObject func(objectKey string) error) error
+	GetLatestRevision(data *entity.Data) error
+	GetUsage(quota *dto.ResponseQuota, userID uuid.UUID) error
+	GetBlob(blob *entity.Blob) error
+	GetRevision(data *entity.Data) error
+	ListRevisions(data *[]entity.Data, userParam dto.Slot) error
 	List(data *[]entity.Data, userParam dto.List) error
 	ListPaged(data *[]entity.Data, userParam dto.List, offset int, limit int) error
 	ListPublic(data *[]entity.Data, userParam dto.List) error
@@ -31,23 +50,205 @@ func (r *DataMySQL) Add(data *entity.Data) error {
 		Error
 }
 
+func (r *DataMySQL) AddWithinQuota(data *entity.Data, limit dto.ResponseQuota, blob *entity.Blob) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := reserveUsage(tx, data.UserID, limit, data.Size, 1)
+		if err != nil {
+			return err
+		}
+
+		if blob != nil {
+			err = acquireBlob(tx, blob)
+			if err != nil {
+				return err
+			}
+
+			data.ObjectKey = blob.ObjectKey
+			data.Compressed = blob.Compressed
+		}
+
+		return tx.Create(data).
+			Error
+	})
+}
+
 func (r *DataMySQL) Retrieve(data *entity.Data, userParam dto.Retrieve) error {
 	return r.db.Debug().
-		Select("type, data").
+		Select("id, type, data, status, version, size, checksum, content_type, game_version").
 		First(data, userParam).
 		Error
 }
 
+func (r *DataMySQL) GetAccess(data *entity.Data) error {
+	return r.db.Debug().
+		Select(dataColumns).
+		First(data).
+		Error
+}
+
+// ConfirmUpload claims a pending data before anything else, so only one of
+// concurrent confirmations references the blob and sets the final status.
+func (r *DataMySQL) ConfirmUpload(data *entity.Data, blob *entity.Blob) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&entity.Data{}).
+			Where("id = ?", data.ID).
+			Where("user_id = ?", data.UserID).
+			Where("status = ?", entity.DataStatusPending).
+			Update("status", entity.DataStatusConfirming)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected != 1 {
+			return errors.New("data already confirmed")
+		}
+
+		if blob != nil {
+			err := acquireBlob(tx, blob)
+			if err != nil {
+				return err
+			}
+
+			data.ObjectKey = blob.ObjectKey
+			data.Compressed = blob.Compressed
+		}
+
+		return tx.
+			Model(data).
+			Updates(map[string]any{
+				"status":       data.Status,
+				"object_key":   data.ObjectKey,
+				"compressed":   data.Compressed,
+				"size":         data.Size,
+				"checksum":     data.Checksum,
+				"content_type": data.ContentType,
+			}).
+			Error
+	})
+}
+
+func (r *DataMySQL) ListStale(data *[]entity.Data, status string, before time.Time) error {
+	return r.db.Debug().
+		Select(dataColumns).
+		Where("status = ?", status).
+		Where("created_at < ?", before).
+		Find(data).
+		Error
+}
+
+func (r *DataMySQL) Update(data *entity.Data) error {
+	return update(r.db.Debug(), data)
+}
+
+// UpdateWithinQuota points data to blob and releases the object data pointed
+// to before, so concurrent overwrites and deletes release it only once.
+func (r *DataMySQL) UpdateWithinQuota(data *entity.Data, limit dto.ResponseQuota, addedBytes int64, blob *entity.Blob, deleteObject func(objectKey string) error) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := reserveUsage(tx, data.UserID, limit, addedBytes, 0)
+		if err != nil {
+			return err
+		}
+
+		var previous entity.Data
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select(dataColumns).
+			Where("id = ?", data.ID).
+			Where("user_id = ?", data.UserID).
+			Where("version = ?", data.Version).
+			Take(&previous).
+			Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("data was modified")
+			}
+
+			return err
+		}
+
+		err = acquireBlob(tx, blob)
+		if err != nil {
+			return err
+		}
+
+		data.ObjectKey = blob.ObjectKey
+		data.Compressed = blob.Compressed
+
+		err = update(tx, data)
+		if err != nil {
+			return err
+		}
+
+		return releaseBlob(tx, previous.Key(), deleteObject)
+	})
+}
+
+func update(tx *gorm.DB, data *entity.Data) error {
+	result := tx.
+		Model(data).
+		Where("user_id = ?", data.UserID).
+		Where("version = ?", data.Version).
+		Updates(map[string]any{
+			"type":         data.Type,
+			"status":       data.Status,
+			"object_key":   data.ObjectKey,
+			"compressed":   data.Compressed,
+			"data":         data.Data,
+			"size":         data.Size,
+			"checksum":     data.Checksum,
+			"content_type": data.ContentType,
+			"game_version": data.GameVersion,
+			"version":      gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("data was modified")
+	}
+
+	return nil
+}
+
+// Delete removes data and releases its object in one transaction, so the
+// object is only released by the delete that actually removed the row.
+func (r *DataMySQL) Delete(data *entity.Data, deleteObject func(objectKey string) error) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select(dataColumns).
+			Where("id = ?", data.ID).
+			Where("user_id = ?", data.UserID).
+			Take(data).
+			Error
+		if err != nil {
+			return err
+		}
+
+		result := tx.Delete(&entity.Data{}, "id = ?", data.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected != 1 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return releaseBlob(tx, data.Key(), deleteObject)
+	})
+}
+
 func (r *DataMySQL) List(data *[]entity.Data, userParam dto.List) error {
 	return r.db.Debug().
-		Select("id, type, created_at").
+		Select(dataColumns).
 		Find(data, userParam).
 		Error
 }
 
 func (r *DataMySQL) ListPaged(data *[]entity.Data, userParam dto.List, offset int, limit int) error {
 	return r.db.Debug().
-		Select("id, type, created_at").
+		Select(dataColumns).
 		Limit(limit).
 		Offset(offset).
 		Find(data, userParam).
@@ -56,18 +257,187 @@ func (r *DataMySQL) ListPaged(data *[]entity.Data, userParam dto.List, offset in
 
 func (r *DataMySQL) ListPublic(data *[]entity.Data, userParam dto.List) error {
 	return r.db.Debug().
-		Select("id, type, created_at").
+		Select(dataColumns).
 		Where("type = ? ", true).
+		Where("status = ?", entity.DataStatusStored).
 		Find(data, userParam).
 		Error
 }
 
 func (r *DataMySQL) ListPublicPaged(data *[]entity.Data, userParam dto.List, offset int, limit int) error {
 	return r.db.Debug().
-		Select("id, type, created_at").
+		Select(dataColumns).
 		Where("type = ? ", true).
+		Where("status = ?", entity.DataStatusStored).
 		Limit(limit).
 		Offset(offset).
 		Find(data, userParam).
 		Error
 }
+
+func (r *DataMySQL) GetLatestRevision(data *entity.Data) error {
+	return r.db.Debug().
+		Select(dataColumns).
+		Where("user_id = ?", data.UserID).
+		Where("slot = ?", data.Slot).
+		Order("revision desc").
+		Take(data).
+		Error
+}
+
+func (r *DataMySQL) GetRevision(data *entity.Data) error {
+	return r.db.Debug().
+		Select(dataColumns).
+		Where("user_id = ?", data.UserID).
+		Where("slot = ?", data.Slot).
+		Where("revision = ?", data.Revision).
+		Take(data).
+		Error
+}
+
+func (r *DataMySQL) ListRevisions(data *[]entity.Data, userParam dto.Slot) error {
+	return r.db.Debug().
+		Select(dataColumns).
+		Where("user_id = ?", userParam.UserID).
+		Where("slot = ?", userParam.Slot).
+		Order("revision desc").
+		Find(data).
+		Error
+}
+
+func (r *DataMySQL) GetUsage(quota *dto.ResponseQuota, userID uuid.UUID) error {
+	return usage(r.db.Debug(), userID).
+		Scan(quota).
+		Error
+}
+
+func usage(tx *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return tx.
+		Model(&entity.Data{}).
+		Select("COUNT(*) AS used_count, COALESCE(SUM(size), 0) AS used_bytes").
+		Where("user_id = ?", userID).
+		Where("status <> ?", entity.DataStatusFailed)
+}
+
+func reserveUsage(tx *gorm.DB, userID uuid.UUID, limit dto.ResponseQuota, addedBytes int64, addedCount int64) error {
+	dataUsage := entity.DataUsage{
+		UserID: userID,
+	}
+
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&dataUsage).
+		Error
+	if err != nil {
+		return err
+	}
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Take(&dataUsage).
+		Error
+	if err != nil {
+		return err
+	}
+
+	var quota dto.ResponseQuota
+
+	err = usage(tx, userID).
+		Scan(&quota).
+		Error
+	if err != nil {
+		return err
+	}
+
+	if limit.LimitBytes > 0 && quota.UsedBytes+addedBytes > limit.LimitBytes {
+		return errors.New("quota exceeded")
+	}
+
+	if limit.LimitCount > 0 && quota.UsedCount+addedCount > limit.LimitCount {
+		return errors.New("quota exceeded")
+	}
+
+	return tx.Model(&dataUsage).
+		Updates(map[string]any{
+			"used_bytes": quota.UsedBytes + addedBytes,
+			"used_count": quota.UsedCount + addedCount,
+		}).
+		Error
+}
+
+func (r *DataMySQL) GetBlob(blob *entity.Blob) error {
+	return r.db.Debug().
+		Where("checksum = ?", blob.Checksum).
+		Take(blob).
+		Error
+}
+
+// acquireBlob references the blob with the checksum of blob, registering blob
+// if there is none. A blob that was not just uploaded must still be
+// registered, otherwise its object was deleted after the last lookup.
+func acquireBlob(tx *gorm.DB, blob *entity.Blob) error {
+	uploaded := blob.Uploaded
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&entity.Blob{
+			Checksum:   blob.Checksum,
+			ObjectKey:  blob.ObjectKey,
+			Compressed: blob.Compressed,
+			Size:       blob.Size,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 1 && !uploaded {
+		return errors.New("blob released")
+	}
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("checksum = ?", blob.Checksum).
+		Take(blob).
+		Error
+	if err != nil {
+		return err
+	}
+
+	blob.Uploaded = uploaded
+	blob.ReferenceCount++
+
+	return tx.Model(blob).
+		Update("reference_count", blob.ReferenceCount).
+		Error
+}
+
+// releaseBlob drops a reference to the blob stored at objectKey and deletes
+// the object once nothing references it. An object without a blob belongs to
+// a single data and is deleted right away.
+func releaseBlob(tx *gorm.DB, objectKey string, deleteObject func(objectKey string) error) error {
+	var blob entity.Blob
+
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("object_key = ?", objectKey).
+		Take(&blob).
+		Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return deleteObject(objectKey)
+		}
+
+		return err
+	}
+
+	blob.ReferenceCount--
+
+	if blob.ReferenceCount <= 0 {
+		err = tx.Delete(&blob).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return deleteObject(blob.ObjectKey)
+	}
+
+	return tx.Model(&blob).
+		Update("reference_count", blob.ReferenceCount).
+		Error
+}
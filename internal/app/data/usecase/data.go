@@ -1,48 +1,408 @@
 package usecase
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
 	"github.com/estella-studio/atr-backend/internal/app/data/repository"
 	"github.com/estella-studio/atr-backend/internal/domain/dto"
 	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/estella-studio/atr-backend/internal/infra/env"
 	"github.com/estella-studio/atr-backend/internal/infra/jwt"
+	"github.com/estella-studio/atr-backend/internal/infra/s3"
+	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 )
 
 type DataUseCaseItf interface {
-	Add(add dto.Add) (dto.ResponseAdd, error)
+	Add(add dto.Add, object []byte) (dto.ResponseAdd, error)
+	AddPending(add dto.Add) (dto.ResponseAdd, error)
+	Confirm(confirm dto.Confirm) (dto.ResponseAdd, error)
 	Retrieve(retrieve dto.Retrieve) (dto.ResponseRetrieve, error)
+	Download(download dto.Download) (dto.ResponseDownload, error)
+	Overwrite(overwrite dto.Overwrite, object []byte) (dto.ResponseAdd, error)
+	Delete(deleteData dto.Delete) error
 	List(userID uuid.UUID, offset int, limit int) (*[]dto.ResponseList, error)
 	ListPublic(userID uuid.UUID, offset int, limit int) (*[]dto.ResponseList, error)
+	ListRevisions(slot dto.Slot) (*[]dto.ResponseList, error)
+	Restore(restore dto.Restore) (dto.ResponseAdd, error)
+	Prune(prune dto.Prune) (dto.ResponsePrune, error)
+	Quota(userID uuid.UUID) (dto.ResponseQuota, error)
+	Reconcile() error
 }
 
 type DataUseCase struct {
 	dataRepo repository.DataMySQLItf
 	jwt      jwt.JWTItf
+	s3       s3.S3Itf
+	config   *env.Env
 }
 
-func NewDataUseCase(dataRepo repository.DataMySQLItf, jwt *jwt.JWT) DataUseCaseItf {
+func NewDataUseCase(dataRepo repository.DataMySQLItf, jwt *jwt.JWT, s3 s3.S3Itf, config *env.Env) DataUseCaseItf {
 	return &DataUseCase{
 		dataRepo: dataRepo,
 		jwt:      jwt,
+		s3:       s3,
+		config:   config,
 	}
 }
 
-func (d *DataUseCase) Add(add dto.Add) (dto.ResponseAdd, error) {
+func (d *DataUseCase) Add(add dto.Add, object []byte) (dto.ResponseAdd, error) {
+	checksum := calculateChecksum(object)
+	if add.Checksum != "" && !strings.EqualFold(add.Checksum, checksum) {
+		return dto.ResponseAdd{}, errors.New("checksum mismatch")
+	}
+
+	revision, err := d.nextRevision(add.UserID, add.Slot, add.IfMatch)
+	if err != nil {
+		return revision.ParseToDTOResponseAdd(), err
+	}
+
 	data := entity.Data{
-		ID:     add.ID,
-		UserID: add.UserID,
-		Type:   add.Type,
-		Data:   add.Data,
+		ID:          add.ID,
+		UserID:      add.UserID,
+		Slot:        add.Slot,
+		Revision:    revision.Revision,
+		ObjectKey:   add.ID.String(),
+		Version:     1,
+		Size:        int64(len(object)),
+		Checksum:    checksum,
+		ContentType: add.ContentType,
+		GameVersion: add.GameVersion,
+		Type:        add.Type,
+		Data:        add.Data,
+		Status:      entity.DataStatusPending,
 	}
 
-	err := d.dataRepo.Add(&data)
+	err = d.dataRepo.AddWithinQuota(&data, d.quotaLimit(), nil)
 	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return d.revisionConflict(add.UserID, add.Slot)
+		}
+
 		return dto.ResponseAdd{}, err
 	}
 
+	data.Status = entity.DataStatusStored
+
+	_, err = d.withBlob(checksum, object, func(blob *entity.Blob) error {
+		return d.dataRepo.ConfirmUpload(&data, blob)
+	})
+	if err != nil {
+		if !strings.Contains(err.Error(), "failed to upload data") {
+			return dto.ResponseAdd{}, err
+		}
+
+		data.Status = entity.DataStatusFailed
+
+		confirmErr := d.dataRepo.ConfirmUpload(&data, nil)
+		if confirmErr != nil {
+			return dto.ResponseAdd{}, confirmErr
+		}
+
+		return data.ParseToDTOResponseAdd(), err
+	}
+
+	data.Data = fmt.Sprintf("%s/%s", d.config.S3BucketURLPrefix, data.ObjectKey)
+
 	return data.ParseToDTOResponseAdd(), nil
 }
 
+func (d *DataUseCase) nextRevision(userID uuid.UUID, slot string, ifMatch string) (entity.Data, error) {
+	if slot == "" {
+		return entity.Data{}, nil
+	}
+
+	latest := entity.Data{
+		UserID: userID,
+		Slot:   slot,
+	}
+
+	err := d.dataRepo.GetLatestRevision(&latest)
+	if err != nil {
+		if !strings.Contains(err.Error(), "record not found") {
+			return entity.Data{}, err
+		}
+
+		if ifMatch != "" {
+			return entity.Data{}, errors.New("data was modified")
+		}
+
+		return entity.Data{Revision: 1}, nil
+	}
+
+	if ifMatch != "" && !latest.MatchETag(ifMatch) {
+		return latest, errors.New("data was modified")
+	}
+
+	return entity.Data{Revision: latest.Revision + 1}, nil
+}
+
+func (d *DataUseCase) revisionConflict(userID uuid.UUID, slot string) (dto.ResponseAdd, error) {
+	latest := entity.Data{
+		UserID: userID,
+		Slot:   slot,
+	}
+
+	err := d.dataRepo.GetLatestRevision(&latest)
+	if err != nil {
+		log.Println(err)
+	}
+
+	return latest.ParseToDTOResponseAdd(), errors.New("data was modified")
+}
+
+func calculateChecksum(object []byte) string {
+	checksum := sha256.Sum256(object)
+
+	return hex.EncodeToString(checksum[:])
+}
+
+func compress(object []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	writer := gzip.NewWriter(&buffer)
+
+	_, err := writer.Write(object)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// withBlob stores object as a blob and passes it to reference, which must
+// acquire the blob in the same transaction that points a data to it. The blob
+// is uploaded again if it was released between the lookup and reference.
+func (d *DataUseCase) withBlob(checksum string, object []byte, reference func(blob *entity.Blob) error) (entity.Blob, error) {
+	blob, err := d.storeBlob(checksum, object, false)
+	if err != nil {
+		return entity.Blob{}, err
+	}
+
+	err = reference(&blob)
+	if err == nil || !strings.Contains(err.Error(), "blob released") {
+		return blob, err
+	}
+
+	blob, err = d.storeBlob(checksum, object, true)
+	if err != nil {
+		return entity.Blob{}, err
+	}
+
+	return blob, reference(&blob)
+}
+
+// storeBlob returns the registered blob with the checksum, or uploads object
+// when there is none or upload is set. The blob is not referenced yet.
+func (d *DataUseCase) storeBlob(checksum string, object []byte, upload bool) (entity.Blob, error) {
+	blob := entity.Blob{
+		Checksum: checksum,
+	}
+
+	if !upload {
+		err := d.dataRepo.GetBlob(&blob)
+		if err == nil {
+			return blob, nil
+		}
+
+		if !strings.Contains(err.Error(), "record not found") {
+			return entity.Blob{}, err
+		}
+	}
+
+	blob = entity.Blob{
+		Checksum:  checksum,
+		ObjectKey: fmt.Sprintf("blobs/%s", checksum),
+		Size:      int64(len(object)),
+		Uploaded:  true,
+	}
+
+	payload := object
+	contentEncoding := ""
+
+	compressed, err := compress(object)
+	if err == nil && len(compressed) < len(object) {
+		payload = compressed
+		contentEncoding = "gzip"
+		blob.Compressed = true
+		blob.Size = int64(len(compressed))
+	}
+
+	err = d.upload(blob.ObjectKey, payload, fiber.MIMEOctetStream, contentEncoding)
+	if err != nil {
+		log.Println(err)
+
+		return entity.Blob{}, errors.New("failed to upload data")
+	}
+
+	return blob, nil
+}
+
+func (d *DataUseCase) deleteObject(objectKey string) error {
+	return d.s3.Delete(context.Background(), objectKey)
+}
+
+func (d *DataUseCase) upload(objectKey string, object []byte, contentType string, contentEncoding string) error {
+	var err error
+
+	backoff := time.Duration(d.config.S3UploadRetryBackoffMilliseconds) * time.Millisecond
+
+	for attempt := 0; attempt <= d.config.S3UploadRetryCount; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		err = d.s3.Upload(context.Background(), objectKey, object, contentType, contentEncoding)
+		if err == nil {
+			return nil
+		}
+
+		log.Println(err)
+	}
+
+	return err
+}
+
+func (d *DataUseCase) AddPending(add dto.Add) (dto.ResponseAdd, error) {
+	revision, err := d.nextRevision(add.UserID, add.Slot, add.IfMatch)
+	if err != nil {
+		return revision.ParseToDTOResponseAdd(), err
+	}
+
+	data := entity.Data{
+		ID:          add.ID,
+		UserID:      add.UserID,
+		Slot:        add.Slot,
+		Revision:    revision.Revision,
+		ObjectKey:   add.ID.String(),
+		Version:     1,
+		Size:        add.Size,
+		Checksum:    strings.ToLower(add.Checksum),
+		ContentType: add.ContentType,
+		GameVersion: add.GameVersion,
+		Type:        add.Type,
+		Data:        add.Data,
+		Status:      entity.DataStatusPending,
+	}
+
+	err = d.dataRepo.AddWithinQuota(&data, d.quotaLimit(), nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return d.revisionConflict(add.UserID, add.Slot)
+		}
+
+		return dto.ResponseAdd{}, err
+	}
+
+	return data.ParseToDTOResponseAdd(), nil
+}
+
+func (d *DataUseCase) Confirm(confirm dto.Confirm) (dto.ResponseAdd, error) {
+	data := entity.Data{
+		ID: confirm.ID,
+	}
+
+	err := d.dataRepo.GetAccess(&data)
+	if err != nil {
+		return dto.ResponseAdd{}, err
+	}
+
+	if data.UserID != confirm.UserID {
+		return dto.ResponseAdd{}, errors.New("data not accessible")
+	}
+
+	if data.Status != entity.DataStatusPending {
+		return dto.ResponseAdd{}, errors.New("data already confirmed")
+	}
+
+	err = d.confirmUpload(&data)
+	if err != nil {
+		if errors.Is(err, s3.ErrObjectNotFound) {
+			return dto.ResponseAdd{}, errors.New("data not uploaded")
+		}
+
+		return data.ParseToDTOResponseAdd(), err
+	}
+
+	return data.ParseToDTOResponseAdd(), nil
+}
+
+func (d *DataUseCase) confirmUpload(data *entity.Data) error {
+	object, err := d.s3.Head(context.Background(), data.Key())
+	if err != nil {
+		return err
+	}
+
+	checksum := data.Checksum
+	if checksum == "" {
+		checksum = object.Checksum
+	}
+
+	size := object.ContentLength
+
+	var confirmErr error
+
+	if checksum == "" || (object.Checksum != "" && object.Checksum != checksum) {
+		confirmErr = errors.New("checksum mismatch")
+	} else {
+		confirmErr = d.checkQuota(data.UserID, size-data.Size, 0)
+	}
+
+	data.Size = size
+	data.Checksum = checksum
+	data.Status = entity.DataStatusStored
+
+	if object.ContentType != "" {
+		data.ContentType = object.ContentType
+	}
+
+	uploadKey := data.Key()
+
+	var blob *entity.Blob
+
+	if confirmErr != nil {
+		data.Status = entity.DataStatusFailed
+	} else {
+		blob = &entity.Blob{
+			Checksum:  checksum,
+			ObjectKey: uploadKey,
+			Size:      size,
+			Uploaded:  true,
+		}
+	}
+
+	err = d.dataRepo.ConfirmUpload(data, blob)
+	if err != nil {
+		return err
+	}
+
+	// The uploaded object is only kept as the blob of a stored data that has
+	// no other blob with the same checksum.
+	if data.Status == entity.DataStatusFailed || data.ObjectKey != uploadKey {
+		err = d.s3.Delete(context.Background(), uploadKey)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	return confirmErr
+}
+
 func (d *DataUseCase) Retrieve(retrieve dto.Retrieve) (dto.ResponseRetrieve, error) {
 	data := entity.Data{
 		ID:     retrieve.ID,
@@ -57,6 +417,105 @@ func (d *DataUseCase) Retrieve(retrieve dto.Retrieve) (dto.ResponseRetrieve, err
 	return data.ParseToDTOResponseRetrieve(), nil
 }
 
+func (d *DataUseCase) Download(download dto.Download) (dto.ResponseDownload, error) {
+	data := entity.Data{
+		ID: download.ID,
+	}
+
+	err := d.dataRepo.GetAccess(&data)
+	if err != nil {
+		return dto.ResponseDownload{}, err
+	}
+
+	if data.UserID != download.UserID && !data.Type {
+		return dto.ResponseDownload{}, errors.New("data not accessible")
+	}
+
+	return data.ParseToDTOResponseDownload(), nil
+}
+
+func (d *DataUseCase) Overwrite(overwrite dto.Overwrite, object []byte) (dto.ResponseAdd, error) {
+	data := entity.Data{
+		ID: overwrite.ID,
+	}
+
+	err := d.dataRepo.GetAccess(&data)
+	if err != nil {
+		return dto.ResponseAdd{}, err
+	}
+
+	if data.UserID != overwrite.UserID {
+		return dto.ResponseAdd{}, errors.New("data not accessible")
+	}
+
+	if data.Status == entity.DataStatusPending {
+		return dto.ResponseAdd{}, errors.New("data upload in progress")
+	}
+
+	if overwrite.IfMatch != "" && !data.MatchETag(overwrite.IfMatch) {
+		return data.ParseToDTOResponseAdd(), errors.New("data was modified")
+	}
+
+	checksum := calculateChecksum(object)
+	if overwrite.Checksum != "" && !strings.EqualFold(overwrite.Checksum, checksum) {
+		return dto.ResponseAdd{}, errors.New("checksum mismatch")
+	}
+
+	addedBytes := int64(len(object)) - data.Size
+
+	data.Type = overwrite.Type
+	data.Status = entity.DataStatusStored
+	data.Size = int64(len(object))
+	data.Checksum = checksum
+	data.ContentType = overwrite.ContentType
+	data.GameVersion = overwrite.GameVersion
+
+	_, err = d.withBlob(checksum, object, func(blob *entity.Blob) error {
+		data.Data = fmt.Sprintf("%s/%s", d.config.S3BucketURLPrefix, blob.ObjectKey)
+
+		return d.dataRepo.UpdateWithinQuota(&data, d.quotaLimit(), addedBytes, blob, d.deleteObject)
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "data was modified") {
+			current := entity.Data{
+				ID: overwrite.ID,
+			}
+
+			_ = d.dataRepo.GetAccess(&current)
+
+			return current.ParseToDTOResponseAdd(), err
+		}
+
+		return dto.ResponseAdd{}, err
+	}
+
+	err = d.dataRepo.GetAccess(&data)
+	if err != nil {
+		return dto.ResponseAdd{}, err
+	}
+
+	return data.ParseToDTOResponseAdd(), nil
+}
+
+func (d *DataUseCase) Delete(deleteData dto.Delete) error {
+	data := entity.Data{
+		ID: deleteData.ID,
+	}
+
+	err := d.dataRepo.GetAccess(&data)
+	if err != nil {
+		return err
+	}
+
+	if data.UserID != deleteData.UserID {
+		return errors.New("data not accessible")
+	}
+
+	err = d.dataRepo.Delete(&data, d.deleteObject)
+
+	return err
+}
+
 func (d *DataUseCase) List(userID uuid.UUID, offset int, limit int) (*[]dto.ResponseList, error) {
 	data := new([]entity.Data)
 
@@ -106,3 +565,228 @@ func (d *DataUseCase) ListPublic(userID uuid.UUID, offset int, limit int) (*[]dt
 
 	return &res, nil
 }
+
+func (d *DataUseCase) ListRevisions(slot dto.Slot) (*[]dto.ResponseList, error) {
+	data := new([]entity.Data)
+
+	err := d.dataRepo.ListRevisions(data, slot)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseList, len(*data))
+
+	for i, data := range *data {
+		res[i] = data.ParseToDTOResponseList()
+	}
+
+	return &res, nil
+}
+
+func (d *DataUseCase) Restore(restore dto.Restore) (dto.ResponseAdd, error) {
+	source := entity.Data{
+		UserID:   restore.UserID,
+		Slot:     restore.Slot,
+		Revision: restore.Revision,
+	}
+
+	err := d.dataRepo.GetRevision(&source)
+	if err != nil {
+		return dto.ResponseAdd{}, err
+	}
+
+	if source.Status != entity.DataStatusStored {
+		return dto.ResponseAdd{}, errors.New("data is not ready")
+	}
+
+	revision, err := d.nextRevision(restore.UserID, restore.Slot, restore.IfMatch)
+	if err != nil {
+		return revision.ParseToDTOResponseAdd(), err
+	}
+
+	data := entity.Data{
+		ID:          uuid.New(),
+		UserID:      restore.UserID,
+		Slot:        restore.Slot,
+		Revision:    revision.Revision,
+		Version:     1,
+		Size:        source.Size,
+		Checksum:    source.Checksum,
+		ContentType: source.ContentType,
+		GameVersion: source.GameVersion,
+		Type:        source.Type,
+		Status:      entity.DataStatusStored,
+	}
+
+	blob := &entity.Blob{
+		Checksum: source.Checksum,
+	}
+
+	err = d.dataRepo.GetBlob(blob)
+	if err != nil {
+		if !strings.Contains(err.Error(), "record not found") {
+			return dto.ResponseAdd{}, err
+		}
+
+		blob = nil
+		data.ObjectKey = data.ID.String()
+
+		err = d.s3.Copy(context.Background(), source.Key(), data.Key())
+		if err != nil {
+			return dto.ResponseAdd{}, errors.New("failed to upload data")
+		}
+	}
+
+	err = d.dataRepo.AddWithinQuota(&data, d.quotaLimit(), blob)
+	if err != nil {
+		if blob == nil {
+			deleteErr := d.s3.Delete(context.Background(), data.Key())
+			if deleteErr != nil {
+				log.Println(deleteErr)
+			}
+		}
+
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return d.revisionConflict(restore.UserID, restore.Slot)
+		}
+
+		if strings.Contains(err.Error(), "blob released") {
+			return dto.ResponseAdd{}, errors.New("failed to upload data")
+		}
+
+		return dto.ResponseAdd{}, err
+	}
+
+	data.Data = fmt.Sprintf("%s/%s", d.config.S3BucketURLPrefix, data.ObjectKey)
+
+	return data.ParseToDTOResponseAdd(), nil
+}
+
+func (d *DataUseCase) Prune(prune dto.Prune) (dto.ResponsePrune, error) {
+	data := new([]entity.Data)
+
+	err := d.dataRepo.ListRevisions(data, dto.Slot{UserID: prune.UserID, Slot: prune.Slot})
+	if err != nil {
+		return dto.ResponsePrune{}, err
+	}
+
+	res := dto.ResponsePrune{
+		Slot: prune.Slot,
+	}
+
+	kept := 0
+
+	for _, data := range *data {
+		if data.Status == entity.DataStatusPending {
+			continue
+		}
+
+		if kept < prune.Keep {
+			kept++
+
+			continue
+		}
+
+		err := d.dataRepo.Delete(&data, d.deleteObject)
+		if err != nil {
+			if strings.Contains(err.Error(), "record not found") {
+				continue
+			}
+
+			return res, err
+		}
+
+		res.Removed++
+	}
+
+	return res, nil
+}
+
+func (d *DataUseCase) Quota(userID uuid.UUID) (dto.ResponseQuota, error) {
+	var quota dto.ResponseQuota
+
+	err := d.dataRepo.GetUsage(&quota, userID)
+	if err != nil {
+		return dto.ResponseQuota{}, err
+	}
+
+	limit := d.quotaLimit()
+
+	quota.LimitBytes = limit.LimitBytes
+	quota.LimitCount = limit.LimitCount
+
+	return quota, nil
+}
+
+func (d *DataUseCase) quotaLimit() dto.ResponseQuota {
+	return dto.ResponseQuota{
+		LimitBytes: d.config.DataQuotaMB * 1024 * 1024,
+		LimitCount: d.config.DataQuotaCount,
+	}
+}
+
+func (d *DataUseCase) checkQuota(userID uuid.UUID, addedBytes int64, addedCount int64) error {
+	quota, err := d.Quota(userID)
+	if err != nil {
+		return err
+	}
+
+	if quota.LimitBytes > 0 && quota.UsedBytes+addedBytes > quota.LimitBytes {
+		return errors.New("quota exceeded")
+	}
+
+	if quota.LimitCount > 0 && quota.UsedCount+addedCount > quota.LimitCount {
+		return errors.New("quota exceeded")
+	}
+
+	return nil
+}
+
+func (d *DataUseCase) Reconcile() error {
+	before := time.Now().Add(-time.Duration(d.config.DataPendingExpiryMinutes) * time.Minute)
+
+	pending := new([]entity.Data)
+
+	err := d.dataRepo.ListStale(pending, entity.DataStatusPending, before)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range *pending {
+		err := d.confirmUpload(&data)
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, s3.ErrObjectNotFound) {
+			log.Println(err)
+
+			continue
+		}
+
+		// The row is failed through the same claim as a confirmation so a
+		// concurrent confirmation keeps it.
+		data.Status = entity.DataStatusFailed
+
+		err = d.dataRepo.ConfirmUpload(&data, nil)
+		if err != nil && !strings.Contains(err.Error(), "data already confirmed") {
+			log.Println(err)
+		}
+	}
+
+	failed := new([]entity.Data)
+
+	err = d.dataRepo.ListStale(failed, entity.DataStatusFailed, before)
+	if err != nil {
+		return err
+	}
+
+	for _, data := range *failed {
+		err := d.dataRepo.Delete(&data, d.deleteObject)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/estella-studio/atr-backend/internal/app/data/repository"
+	"github.com/estella-studio/atr-backend/internal/domain/dto"
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/estella-studio/atr-backend/internal/infra/s3"
+	"github.com/google/uuid"
+)
+
+// testDataRepo keeps data and blobs in memory and deletes like DataMySQL: the
+// row and its blob reference are removed under one lock.
+type testDataRepo struct {
+	repository.DataMySQLItf
+
+	mutex  sync.Mutex
+	access sync.WaitGroup
+	data   map[uuid.UUID]entity.Data
+	blobs  map[string]*entity.Blob
+}
+
+func (r *testDataRepo) GetAccess(data *entity.Data) error {
+	r.mutex.Lock()
+	stored, ok := r.data[data.ID]
+	r.mutex.Unlock()
+
+	if !ok {
+		return errors.New("record not found")
+	}
+
+	*data = stored
+
+	// Every concurrent delete reads the row before any of them deletes it.
+	r.access.Done()
+	r.access.Wait()
+
+	return nil
+}
+
+func (r *testDataRepo) Delete(data *entity.Data, deleteObject func(objectKey string) error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	stored, ok := r.data[data.ID]
+	if !ok || stored.UserID != data.UserID {
+		return errors.New("record not found")
+	}
+
+	delete(r.data, data.ID)
+
+	blob, ok := r.blobs[stored.Key()]
+	if !ok {
+		return deleteObject(stored.Key())
+	}
+
+	blob.ReferenceCount--
+
+	if blob.ReferenceCount <= 0 {
+		delete(r.blobs, blob.ObjectKey)
+
+		return deleteObject(blob.ObjectKey)
+	}
+
+	return nil
+}
+
+type testS3 struct {
+	s3.S3Itf
+
+	mutex   sync.Mutex
+	deleted []string
+}
+
+func (s *testS3) Delete(ctx context.Context, objectKey string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.deleted = append(s.deleted, objectKey)
+
+	return nil
+}
+
+func TestDeleteConcurrentlyReleasesBlobOnce(t *testing.T) {
+	objectKey := "blobs/checksum"
+
+	owner := entity.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		ObjectKey: objectKey,
+		Status:    entity.DataStatusStored,
+	}
+
+	other := entity.Data{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		ObjectKey: objectKey,
+		Status:    entity.DataStatusStored,
+	}
+
+	repo := &testDataRepo{
+		data: map[uuid.UUID]entity.Data{
+			owner.ID: owner,
+			other.ID: other,
+		},
+		blobs: map[string]*entity.Blob{
+			objectKey: {
+				Checksum:       "checksum",
+				ObjectKey:      objectKey,
+				ReferenceCount: 2,
+			},
+		},
+	}
+
+	storage := &testS3{}
+
+	dataUseCase := &DataUseCase{
+		dataRepo: repo,
+		s3:       storage,
+	}
+
+	const deletes = 2
+
+	repo.access.Add(deletes)
+
+	errs := make([]error, deletes)
+
+	var wg sync.WaitGroup
+
+	for i := range deletes {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs[i] = dataUseCase.Delete(dto.Delete{
+				ID:     owner.ID,
+				UserID: owner.UserID,
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	failed := 0
+
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+
+	if failed != deletes-1 {
+		t.Fatalf("%d of %d concurrent deletes failed, want %d", failed, deletes, deletes-1)
+	}
+
+	blob, ok := repo.blobs[objectKey]
+	if !ok {
+		t.Fatal("blob released while other data still references it")
+	}
+
+	if blob.ReferenceCount != 1 {
+		t.Fatalf("ReferenceCount = %d, want 1", blob.ReferenceCount)
+	}
+
+	if len(storage.deleted) != 0 {
+		t.Fatalf("deleted objects %v, want none", storage.deleted)
+	}
+}
@@ -0,0 +1,28 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/estella-studio/atr-backend/internal/infra/jwt"
+	"github.com/gofiber/fiber/v2"
+)
+
+type JWKSHandler struct {
+	JWT *jwt.JWT
+}
+
+func NewJWKSHandler(routerGroup fiber.Router, jwt *jwt.JWT) {
+	jwksHandler := JWKSHandler{
+		JWT: jwt,
+	}
+
+	routerGroup = routerGroup.Group("/.well-known")
+
+	routerGroup.Get("/jwks.json", jwksHandler.JWKS)
+}
+
+func (j *JWKSHandler) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return ctx.Status(http.StatusOK).JSON(j.JWT.JWKS())
+}
@@ -18,7 +18,7 @@ func NewPingHandler(routerGroup fiber.Router, middleware middleware.MiddlewareIt
 
 	routerGroup = routerGroup.Group("/ping")
 
-	routerGroup.Get("/", middleware.Authentication, middleware.UserStatus, pingHandler.Ping)
+	routerGroup.Get("/", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, pingHandler.Ping)
 }
 
 func (p *PingHandler) Ping(ctx *fiber.Ctx) error {
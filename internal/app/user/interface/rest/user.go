@@ -1,9 +1,12 @@
 package rest
 
 import (
+	"errors"
+	"fmt"
 	"log"
-	"math/rand"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -43,24 +46,49 @@ func NewUserHandler(
 
 	routerGroup.Post("/register", userHandler.Register)
 	routerGroup.Post("/login", userHandler.Login)
-	routerGroup.Get("/renewtoken", middleware.Authentication, middleware.UserStatus, userHandler.RenewToken)
-	routerGroup.Post("/friendrequest", middleware.Authentication, middleware.UserStatus, userHandler.SendFriendRequest)
-	routerGroup.Get("/friendrequestsent", middleware.Authentication, middleware.UserStatus, userHandler.GetFriendRequestSent)
-	routerGroup.Get("/friendrequestreceived", middleware.Authentication, middleware.UserStatus, userHandler.GetFriendRequestReceived)
-	routerGroup.Patch("/friendrequest", middleware.Authentication, middleware.UserStatus, userHandler.AcceptFriendRequest)
-	routerGroup.Get("/friends", middleware.Authentication, middleware.UserStatus, userHandler.GetFriendList)
+	routerGroup.Post("/login/2fa", userHandler.LoginTwoFactor)
+	routerGroup.Post("/guest", userHandler.CreateGuest)
+	routerGroup.Post("/guest/login", userHandler.GuestLogin)
+	routerGroup.Post("/guest/upgrade", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.UpgradeGuest)
+	routerGroup.Post("/guest/upgrade/:provider", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.UpgradeGuestWithIdentity)
+	routerGroup.Post("/passwordless", userHandler.RequestPasswordlessLogin)
+	routerGroup.Post("/passwordless/verify", userHandler.PasswordlessLogin)
+	routerGroup.Get("/oauth/providers", userHandler.OAuthProviders)
+	routerGroup.Post("/oauth/:provider/authorize", userHandler.OAuthAuthorize)
+	routerGroup.Post("/oauth/:provider/callback", userHandler.OAuthLogin)
+	routerGroup.Get("/identities", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.ListLinkedIdentities)
+	routerGroup.Post("/identities/:provider/authorize", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.OAuthAuthorize)
+	routerGroup.Post("/identities/:provider", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.OAuthLink)
+	routerGroup.Delete("/identities/:id", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.UnlinkIdentity)
+	routerGroup.Post("/2fa/enroll", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.EnrollTwoFactor)
+	routerGroup.Post("/2fa/confirm", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.ConfirmTwoFactor)
+	routerGroup.Post("/2fa/disable", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.DisableTwoFactor)
+	routerGroup.Post("/2fa/recovery-codes", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.RegenerateRecoveryCodes)
+	routerGroup.Post("/token/refresh", userHandler.RefreshToken)
+	routerGroup.Post("/logout", middleware.SessionAuthentication, userHandler.Logout)
+	routerGroup.Post("/logout-all", middleware.SessionAuthentication, userHandler.LogoutAll)
+	routerGroup.Get("/sessions", middleware.Authentication, middleware.UserStatus, userHandler.ListSessions)
+	routerGroup.Delete("/sessions/:id", middleware.Authentication, middleware.UserStatus, userHandler.RevokeSession)
+	routerGroup.Post("/friendrequest", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.SendFriendRequest)
+	routerGroup.Get("/friendrequestsent", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.GetFriendRequestSent)
+	routerGroup.Get("/friendrequestreceived", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.GetFriendRequestReceived)
+	routerGroup.Patch("/friendrequest", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.AcceptFriendRequest)
+	routerGroup.Get("/friends", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.GetFriendList)
 	routerGroup.Post("/emailverification", userHandler.NewEmailVerification)
 	routerGroup.Post("/validateemail", userHandler.ValidateEmail)
+	routerGroup.Post("/emailverification/resend", middleware.Authentication, middleware.UserStatus, userHandler.ResendEmailVerification)
+	routerGroup.Post("/verifyemail", middleware.Authentication, middleware.UserStatus, userHandler.VerifyEmail)
 	routerGroup.Get("/checkusername", userHandler.CheckUsername)
 	routerGroup.Get("/info", middleware.Authentication, middleware.UserStatus, userHandler.GetUserInfo)
 	routerGroup.Get("/publicinfo", userHandler.GetUserInfoPublic)
-	routerGroup.Patch("/update", middleware.Authentication, middleware.UserStatus, userHandler.UpdateUserInfo)
+	routerGroup.Patch("/update", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.UpdateUserInfo)
 	routerGroup.Get("/resetpassword", userHandler.ResetPassword)
 	routerGroup.Post("/resetpassword", userHandler.ResetPasswordWithID)
 	routerGroup.Get("/checkpasswordresetcode", userHandler.CheckPasswordResetCode)
 	routerGroup.Post("/resetpasswordwithcode", userHandler.ResetPasswordWithCode)
 	routerGroup.Post("/changepassword", middleware.Authentication, middleware.UserStatus, userHandler.ChangePassword)
-	routerGroup.Post("/report", middleware.Authentication, middleware.UserStatus, userHandler.ReportUser)
+	routerGroup.Post("/report", middleware.Authentication, middleware.UserStatus, middleware.EmailVerified, userHandler.ReportUser)
+	routerGroup.Post("/appeal", userHandler.Appeal)
 	routerGroup.Delete("/delete", middleware.Authentication, middleware.UserStatus, userHandler.SoftDelete)
 }
 
@@ -83,30 +111,25 @@ func (u *UserHandler) Register(ctx *fiber.Ctx) error {
 		)
 	}
 
-	_, validated, err := u.UserUseCase.GetEmailVerification(
-		&dto.EmailVerification{
-			Email: register.Email,
-		},
-	)
-	if err != nil {
-		log.Println(err)
-	}
-
-	if !validated {
-		return fiber.NewError(
-			http.StatusBadRequest,
-			"email not validated",
-		)
-	}
-
 	res, err := u.UserUseCase.Register(register)
 	if err != nil {
+		if strings.Contains(err.Error(), "invalid verification ticket") {
+			return fiber.NewError(
+				http.StatusBadRequest,
+				err.Error(),
+			)
+		}
+
 		return fiber.NewError(
 			http.StatusConflict,
 			"please use another email / username",
 		)
 	}
 
+	if !res.EmailVerified {
+		go u.sendEmailVerification(res.ID)
+	}
+
 	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
 		"message": "user registered",
 		"payload": res,
@@ -132,26 +155,169 @@ func (u *UserHandler) Login(ctx *fiber.Ctx) error {
 		)
 	}
 
+	login.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	login.IPAddress = ctx.IP()
+
 	res, token, err := u.UserUseCase.Login(login)
 	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		var loginThrottled *usecase.LoginThrottledError
+		if errors.As(err, &loginThrottled) {
+			return u.loginThrottledResponse(ctx, loginThrottled)
+		}
+
 		return fiber.NewError(
 			http.StatusUnauthorized,
 			"invalid username or password",
 		)
 	}
 
+	if token.ChallengeToken != "" {
+		return ctx.Status(http.StatusOK).JSON(fiber.Map{
+			"message":         "two factor authentication required",
+			"challenge_token": token.ChallengeToken,
+		})
+	}
+
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
-		"message": "user authenticated",
-		"token":   token,
-		"payload": res,
+		"message":       "user authenticated",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) LoginTwoFactor(ctx *fiber.Ctx) error {
+	var loginTwoFactor dto.LoginTwoFactor
+
+	err := ctx.BodyParser(&loginTwoFactor)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(loginTwoFactor)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	res, token, err := u.UserUseCase.LoginTwoFactor(loginTwoFactor)
+	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		if strings.Contains(err.Error(), "invalid code") {
+			return fiber.NewError(
+				http.StatusUnauthorized,
+				"invalid code",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"invalid challenge token",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message":       "user authenticated",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) RefreshToken(ctx *fiber.Ctx) error {
+	var refreshToken dto.RefreshToken
+
+	err := ctx.BodyParser(&refreshToken)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(refreshToken)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	token, err := u.UserUseCase.RefreshToken(refreshToken)
+	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"invalid refresh token",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message":       "token refreshed",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
 	})
 }
 
-func (u *UserHandler) RenewToken(ctx *fiber.Ctx) error {
-	var renewToken dto.RenewToken
-	var err error
+func (u *UserHandler) Logout(ctx *fiber.Ctx) error {
+	var logout dto.Logout
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	if len(ctx.Body()) > 0 {
+		err = ctx.BodyParser(&logout)
+		if err != nil {
+			return fiber.NewError(
+				http.StatusBadRequest,
+				"failed to parse request body",
+			)
+		}
+	}
+
+	logout.UserID = userID
+	logout.SessionID, _ = uuid.Parse(ctx.Locals("sessionID").(string))
+	logout.TokenID = ctx.Locals("tokenID").(string)
+	logout.ExpiresAt = ctx.Locals("tokenExpiresAt").(time.Time)
+
+	err = u.UserUseCase.Logout(logout)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to logout",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "user logged out",
+	})
+}
 
-	renewToken.ID, err = uuid.Parse(ctx.Locals("userID").(string))
+func (u *UserHandler) LogoutAll(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
 	if err != nil {
 		return fiber.NewError(
 			http.StatusUnauthorized,
@@ -159,7 +325,21 @@ func (u *UserHandler) RenewToken(ctx *fiber.Ctx) error {
 		)
 	}
 
-	token, err := u.UserUseCase.RenewToken(renewToken)
+	err = u.UserUseCase.LogoutAll(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to logout",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "user logged out from all devices",
+	})
+}
+
+func (u *UserHandler) ListSessions(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
 	if err != nil {
 		return fiber.NewError(
 			http.StatusUnauthorized,
@@ -167,12 +347,59 @@ func (u *UserHandler) RenewToken(ctx *fiber.Ctx) error {
 		)
 	}
 
+	sessionID, _ := uuid.Parse(ctx.Locals("sessionID").(string))
+
+	res, err := u.UserUseCase.ListSessions(userID, sessionID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve session list",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
-		"message": "token renewed",
-		"token":   token,
+		"message": "retrieved session list",
+		"payload": res,
 	})
 }
 
+func (u *UserHandler) RevokeSession(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	sessionID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	err = u.UserUseCase.RevokeSession(userID, sessionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"session not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to revoke session",
+		)
+	}
+
+	return ctx.Status(http.StatusNoContent).Context().Err()
+}
+
 func (u *UserHandler) SendFriendRequest(ctx *fiber.Ctx) error {
 	var sendFriendRequest dto.SendFriendRequest
 
@@ -450,30 +677,13 @@ func (u *UserHandler) NewEmailVerification(ctx *fiber.Ctx) error {
 	}
 
 	go func() {
-		var codeString string
-
-		for i := uint(0); i < u.Config.AccountRegistrationCodeDigitCount; i++ {
-			codeString += (string)(rand.Intn(10) + 48)
-		}
-
-		newCode, _ := strconv.Atoi(codeString)
-
-		code, success, err := u.UserUseCase.GetEmailVerification(&emailVerification)
-		if err == nil && success {
+		code, err := u.UserUseCase.NewEmailVerification(&emailVerification)
+		if err != nil {
+			log.Println(err)
 			return
-		} else if err != nil {
-			emailVerification.ID = uuid.New()
-			emailVerification.Code = uint(newCode)
-
-			err = u.UserUseCase.NewEmailVerification(&emailVerification)
-			if err != nil {
-				log.Println(err)
-			}
-		} else {
-			newCode = int(code)
 		}
 
-		err = u.Mailer.AccountRegistration(emailVerification.Email, uint(newCode))
+		err = u.Mailer.AccountRegistration(emailVerification.Email, code)
 		if err != nil {
 			log.Println(err)
 		}
@@ -501,33 +711,112 @@ func (u *UserHandler) ValidateEmail(ctx *fiber.Ctx) error {
 		)
 	}
 
-	err = u.UserUseCase.ValidateEmail(&validateEmail)
+	res, err := u.UserUseCase.ValidateEmail(&validateEmail)
+	if err != nil {
+		return codeError(err, "failed to validate email")
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "email confirmed",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) ResendEmailVerification(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	res, err := u.UserUseCase.ResendEmailVerification(userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "email already verified") {
+			return fiber.NewError(
+				http.StatusConflict,
+				err.Error(),
+			)
+		}
+
+		if strings.Contains(err.Error(), "verification code recently sent") {
+			return fiber.NewError(
+				http.StatusTooManyRequests,
+				err.Error(),
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to send verification code",
+		)
+	}
+
+	go func() {
+		err := u.Mailer.AccountRegistration(res.Email, res.Code)
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "verification code sent",
+	})
+}
+
+func (u *UserHandler) VerifyEmail(ctx *fiber.Ctx) error {
+	var verifyEmail dto.VerifyEmail
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&verifyEmail)
 	if err != nil {
 		return fiber.NewError(
 			http.StatusBadRequest,
-			"invalid code",
+			"failed to parse request body",
 		)
 	}
 
-	code, success, err := u.UserUseCase.GetEmailVerification(
-		&dto.EmailVerification{
-			Email: validateEmail.Email,
-		},
-	)
-	if err != nil ||
-		!success ||
-		(code != validateEmail.Code) {
+	err = u.Validator.Struct(verifyEmail)
+	if err != nil {
 		return fiber.NewError(
 			http.StatusBadRequest,
-			"invalid code",
+			"invalid request body",
 		)
 	}
 
+	verifyEmail.UserID = userID
+
+	err = u.UserUseCase.VerifyEmail(verifyEmail)
+	if err != nil {
+		return codeError(err, "failed to verify email")
+	}
+
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "email confirmed",
 	})
 }
 
+func (u *UserHandler) sendEmailVerification(userID uuid.UUID) {
+	res, err := u.UserUseCase.ResendEmailVerification(userID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	err = u.Mailer.AccountRegistration(res.Email, res.Code)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (u *UserHandler) CheckUsername(ctx *fiber.Ctx) error {
 	var user dto.CheckUsername
 
@@ -638,6 +927,13 @@ func (u *UserHandler) UpdateUserInfo(ctx *fiber.Ctx) error {
 
 	_, err = u.UserUseCase.UpdateUserInfo(user, userID)
 	if err != nil {
+		if strings.Contains(err.Error(), "guest account") {
+			return fiber.NewError(
+				http.StatusForbidden,
+				"upgrade the guest account to set an email",
+			)
+		}
+
 		if strings.Contains(err.Error(), "Duplicate entry") {
 			return fiber.NewError(
 				http.StatusConflict,
@@ -658,6 +954,10 @@ func (u *UserHandler) UpdateUserInfo(ctx *fiber.Ctx) error {
 			"user info updated but failed to retrieve updated content")
 	}
 
+	if user.Email != "" && !res.EmailVerified {
+		go u.sendEmailVerification(userID)
+	}
+
 	return ctx.Status(http.StatusOK).JSON(fiber.Map{
 		"message": "user updated",
 		"payload": res,
@@ -684,53 +984,20 @@ func (u *UserHandler) ResetPassword(ctx *fiber.Ctx) error {
 	}
 
 	go func() {
-		userID, err := u.UserUseCase.GetUserIDFromEmail(user)
+		res, err := u.UserUseCase.CreatePasswordResetCode(user)
 		if err != nil {
 			log.Println(err)
+			return
 		}
 
-		lastRequest, err := u.UserUseCase.GetPasswordResetCodeValidity(userID)
+		err = u.Mailer.PasswordReset(res.Email, res.ID, res.Code)
 		if err != nil {
 			log.Println(err)
 		}
+	}()
 
-		timeSecond := time.Since(lastRequest)
-
-		if timeSecond < time.Duration(u.Config.PasswordChangeCodeRetrySeconds*int(time.Second)) {
-			log.Printf("time since last: %d\n", timeSecond)
-			return
-		}
-
-		changeID := uuid.New()
-		var codeString string
-
-		for i := uint(0); i < u.Config.PasswordChangeCodeDigitcount; i++ {
-			codeString += (string)(rand.Intn(10) + 48)
-		}
-
-		code, _ := strconv.Atoi(codeString)
-
-		err = u.UserUseCase.CreatePasswordChangeEntry(changeID, userID)
-		if err != nil {
-			log.Println(err)
-		}
-
-		err = u.UserUseCase.CreatePasswordResetCode(changeID, userID, uint(code))
-		if err != nil {
-			log.Println(err)
-		}
-
-		err = u.UserUseCase.ResetPassword(user)
-		if err == nil {
-			err := u.Mailer.PasswordReset(user.Email, changeID, uint(code))
-			if err != nil {
-				log.Println(err)
-			}
-		}
-	}()
-
-	return ctx.Status(http.StatusOK).Context().Err()
-}
+	return ctx.Status(http.StatusOK).Context().Err()
+}
 
 func (u *UserHandler) ResetPasswordWithID(ctx *fiber.Ctx) error {
 	var user dto.ChangePassword
@@ -816,25 +1083,9 @@ func (u *UserHandler) CheckPasswordResetCode(ctx *fiber.Ctx) error {
 		)
 	}
 
-	userID, err := u.UserUseCase.GetUserIDFromEmail(
-		dto.ResetPassword{
-			Email: checkPasswordResetCode.Email,
-		},
-	)
+	err = u.UserUseCase.CheckPasswordResetCode(checkPasswordResetCode)
 	if err != nil {
-		return fiber.NewError(
-			http.StatusBadRequest,
-			"invalid email",
-		)
-	}
-
-	_, code, err := u.UserUseCase.GetPasswordResetCode(userID, checkPasswordResetCode.Code)
-	if err != nil ||
-		code != checkPasswordResetCode.Code {
-		return fiber.NewError(
-			http.StatusBadRequest,
-			"invalid code",
-		)
+		return codeError(err, "failed to check code")
 	}
 
 	return ctx.Status(http.StatusOK).Context().Err()
@@ -859,48 +1110,45 @@ func (u *UserHandler) ResetPasswordWithCode(ctx *fiber.Ctx) error {
 		)
 	}
 
-	userID, err := u.UserUseCase.GetUserIDFromEmail(dto.ResetPassword{Email: user.Email})
+	err = u.UserUseCase.ResetPasswordWithCode(user)
 	if err != nil {
+		if strings.Contains(err.Error(), "invalid code") {
+			return fiber.NewError(
+				http.StatusUnauthorized,
+				"invalid code",
+			)
+		}
+
 		return fiber.NewError(
-			http.StatusNoContent,
+			http.StatusInternalServerError,
+			"failed to change passsword",
 		)
 	}
 
-	passwordChagneID, code, err := u.UserUseCase.GetPasswordResetCode(userID, user.Code)
-	if err != nil ||
-		code != user.Code {
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "password changed",
+	})
+}
+
+func codeError(err error, message string) error {
+	if strings.Contains(err.Error(), "invalid code") {
 		return fiber.NewError(
-			http.StatusUnauthorized,
+			http.StatusBadRequest,
 			"invalid code",
 		)
 	}
 
-	user.PasswordChangeId = passwordChagneID
-
-	err = u.UserUseCase.ChangePassword(dto.ChangePassword{Password: user.Password}, userID)
-	if err != nil {
+	if strings.Contains(err.Error(), "email already verified") {
 		return fiber.NewError(
-			http.StatusInternalServerError,
-			"failed to change passsword",
+			http.StatusConflict,
+			err.Error(),
 		)
 	}
 
-	go func() {
-		log.Println(user.PasswordChangeId)
-		err := u.UserUseCase.UpdatePasswordResetCode(user.PasswordChangeId, userID, user.Code)
-		if err != nil {
-			log.Println(err)
-		}
-
-		err = u.UserUseCase.UpdatePasswordChangeEntry(user.PasswordChangeId, userID)
-		if err != nil {
-			log.Println(err)
-		}
-	}()
-
-	return ctx.Status(http.StatusOK).JSON(fiber.Map{
-		"message": "password changed",
-	})
+	return fiber.NewError(
+		http.StatusInternalServerError,
+		message,
+	)
 }
 
 func (u *UserHandler) ChangePassword(ctx *fiber.Ctx) error {
@@ -1004,6 +1252,13 @@ func (u *UserHandler) ReportUser(ctx *fiber.Ctx) error {
 
 	err = u.UserUseCase.ReportUser(reportUser)
 	if err != nil {
+		if strings.Contains(err.Error(), "save data not found") {
+			return fiber.NewError(
+				http.StatusBadRequest,
+				err.Error(),
+			)
+		}
+
 		return fiber.NewError(
 			http.StatusConflict,
 			err.Error(),
@@ -1015,6 +1270,85 @@ func (u *UserHandler) ReportUser(ctx *fiber.Ctx) error {
 	})
 }
 
+func (u *UserHandler) Appeal(ctx *fiber.Ctx) error {
+	var appeal dto.Appeal
+
+	err := ctx.BodyParser(&appeal)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(appeal)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	appeal.IPAddress = ctx.IP()
+
+	res, err := u.UserUseCase.Appeal(appeal)
+	if err != nil {
+		var loginThrottled *usecase.LoginThrottledError
+		if errors.As(err, &loginThrottled) {
+			return u.loginThrottledResponse(ctx, loginThrottled)
+		}
+
+		if strings.Contains(err.Error(), "appeal already submitted") {
+			return fiber.NewError(
+				http.StatusConflict,
+				err.Error(),
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"invalid username or password, or account not restricted",
+		)
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "appeal submitted",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) loginThrottledResponse(ctx *fiber.Ctx, loginThrottled *usecase.LoginThrottledError) error {
+	if loginThrottled.Email != "" {
+		go func() {
+			err := u.Mailer.LoginLockout(loginThrottled.Email, *loginThrottled.LockedUntil)
+			if err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
+	retryAfter := int(math.Ceil(loginThrottled.RetryAfter.Seconds()))
+
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+
+	return ctx.Status(http.StatusTooManyRequests).JSON(fiber.Map{
+		"message": "too many login attempts",
+		"payload": fiber.Map{
+			"retry_after":  retryAfter,
+			"locked_until": loginThrottled.LockedUntil,
+		},
+	})
+}
+
+func accountLockedResponse(ctx *fiber.Ctx, accountLocked *usecase.AccountLockedError) error {
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusForbidden).JSON(fiber.Map{
+		"message": accountLocked.Error(),
+		"payload": accountLocked.Status,
+	})
+}
+
 func (u *UserHandler) SoftDelete(ctx *fiber.Ctx) error {
 	userID, err := uuid.Parse(ctx.Locals("userID").(string))
 	if err != nil {
@@ -1034,3 +1368,701 @@ func (u *UserHandler) SoftDelete(ctx *fiber.Ctx) error {
 
 	return ctx.Status(http.StatusNoContent).Context().Err()
 }
+
+func (u *UserHandler) EnrollTwoFactor(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	res, err := u.UserUseCase.EnrollTwoFactor(userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "two factor already enabled") {
+			return fiber.NewError(
+				http.StatusConflict,
+				err.Error(),
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to enroll two factor authentication",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "two factor authentication enrolled",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) ConfirmTwoFactor(ctx *fiber.Ctx) error {
+	var twoFactorCode dto.TwoFactorCode
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&twoFactorCode)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(twoFactorCode)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	twoFactorCode.UserID = userID
+
+	res, err := u.UserUseCase.ConfirmTwoFactor(twoFactorCode)
+	if err != nil {
+		return twoFactorError(err, "failed to enable two factor authentication")
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "two factor authentication enabled",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) DisableTwoFactor(ctx *fiber.Ctx) error {
+	var disableTwoFactor dto.DisableTwoFactor
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&disableTwoFactor)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(disableTwoFactor)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	disableTwoFactor.UserID = userID
+
+	err = u.UserUseCase.DisableTwoFactor(disableTwoFactor)
+	if err != nil {
+		return twoFactorError(err, "failed to disable two factor authentication")
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "two factor authentication disabled",
+	})
+}
+
+func (u *UserHandler) RegenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	var twoFactorCode dto.TwoFactorCode
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&twoFactorCode)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(twoFactorCode)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	twoFactorCode.UserID = userID
+
+	res, err := u.UserUseCase.RegenerateRecoveryCodes(twoFactorCode)
+	if err != nil {
+		return twoFactorError(err, "failed to regenerate recovery codes")
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "recovery codes regenerated",
+		"payload": res,
+	})
+}
+
+func twoFactorError(err error, message string) error {
+	if strings.Contains(err.Error(), "invalid code") ||
+		strings.Contains(err.Error(), "invalid password") {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "two factor already enabled") ||
+		strings.Contains(err.Error(), "two factor not enabled") ||
+		strings.Contains(err.Error(), "two factor not enrolled") {
+		return fiber.NewError(
+			http.StatusConflict,
+			err.Error(),
+		)
+	}
+
+	return fiber.NewError(
+		http.StatusInternalServerError,
+		message,
+	)
+}
+
+func (u *UserHandler) OAuthProviders(ctx *fiber.Ctx) error {
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved identity providers",
+		"payload": u.UserUseCase.OAuthProviders(),
+	})
+}
+
+func (u *UserHandler) OAuthAuthorize(ctx *fiber.Ctx) error {
+	oauthAuthorize := dto.OAuthAuthorize{
+		Provider: ctx.Params("provider"),
+	}
+
+	if userID, ok := ctx.Locals("userID").(string); ok {
+		oauthAuthorize.UserID, _ = uuid.Parse(userID)
+	}
+
+	res, err := u.UserUseCase.OAuthAuthorize(oauthAuthorize)
+	if err != nil {
+		return oauthError(err, "failed to create authorization url")
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "authorization url created",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) OAuthLogin(ctx *fiber.Ctx) error {
+	var oauthCallback dto.OAuthCallback
+
+	err := ctx.BodyParser(&oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	oauthCallback.Provider = ctx.Params("provider")
+	oauthCallback.UserID = uuid.Nil
+	oauthCallback.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	oauthCallback.IPAddress = ctx.IP()
+
+	res, token, err := u.UserUseCase.OAuthLogin(oauthCallback)
+	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		var loginThrottled *usecase.LoginThrottledError
+		if errors.As(err, &loginThrottled) {
+			return u.loginThrottledResponse(ctx, loginThrottled)
+		}
+
+		return oauthError(err, "failed to login")
+	}
+
+	if token.ChallengeToken != "" {
+		return ctx.Status(http.StatusOK).JSON(fiber.Map{
+			"message":         "two factor authentication required",
+			"challenge_token": token.ChallengeToken,
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message":       "user authenticated",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) OAuthLink(ctx *fiber.Ctx) error {
+	var oauthCallback dto.OAuthCallback
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	oauthCallback.Provider = ctx.Params("provider")
+	oauthCallback.UserID = userID
+
+	res, err := u.UserUseCase.OAuthLink(oauthCallback)
+	if err != nil {
+		return oauthError(err, "failed to link identity")
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message": "identity linked",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) ListLinkedIdentities(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	res, err := u.UserUseCase.ListLinkedIdentities(userID)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to retrieve linked identities",
+		)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "retrieved linked identities",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) UnlinkIdentity(ctx *fiber.Ctx) error {
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	identityID, err := uuid.Parse(ctx.Params("id"))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid id",
+		)
+	}
+
+	err = u.UserUseCase.UnlinkIdentity(userID, identityID)
+	if err != nil {
+		if strings.Contains(err.Error(), "record not found") {
+			return fiber.NewError(
+				http.StatusNotFound,
+				"identity not found",
+			)
+		}
+
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to unlink identity",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "identity unlinked",
+	})
+}
+
+func oauthError(err error, message string) error {
+	if strings.Contains(err.Error(), "provider not supported") {
+		return fiber.NewError(
+			http.StatusNotFound,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "invalid state") ||
+		strings.Contains(err.Error(), "verified email required") {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "identity provider rejected the login") {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "email already registered") ||
+		strings.Contains(err.Error(), "identity already linked") {
+		return fiber.NewError(
+			http.StatusConflict,
+			err.Error(),
+		)
+	}
+
+	return fiber.NewError(
+		http.StatusInternalServerError,
+		message,
+	)
+}
+
+func (u *UserHandler) RequestPasswordlessLogin(ctx *fiber.Ctx) error {
+	var passwordlessRequest dto.PasswordlessRequest
+
+	err := ctx.BodyParser(&passwordlessRequest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(passwordlessRequest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	go func() {
+		loginCode, err := u.UserUseCase.CreateLoginCode(passwordlessRequest)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+
+		link := fmt.Sprintf("%s?token=%s", u.Config.PasswordlessLinkURL, url.QueryEscape(loginCode.Token))
+
+		err = u.Mailer.PasswordlessLogin(loginCode.Email, loginCode.Code, link, loginCode.ExpiresAt)
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "login code sent if the email is registered",
+	})
+}
+
+func (u *UserHandler) PasswordlessLogin(ctx *fiber.Ctx) error {
+	var passwordlessLogin dto.PasswordlessLogin
+
+	err := ctx.BodyParser(&passwordlessLogin)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(passwordlessLogin)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	passwordlessLogin.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	passwordlessLogin.IPAddress = ctx.IP()
+
+	res, token, err := u.UserUseCase.PasswordlessLogin(passwordlessLogin)
+	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		var loginThrottled *usecase.LoginThrottledError
+		if errors.As(err, &loginThrottled) {
+			return u.loginThrottledResponse(ctx, loginThrottled)
+		}
+
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"invalid login code",
+		)
+	}
+
+	if token.ChallengeToken != "" {
+		return ctx.Status(http.StatusOK).JSON(fiber.Map{
+			"message":         "two factor authentication required",
+			"challenge_token": token.ChallengeToken,
+		})
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message":       "user authenticated",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) CreateGuest(ctx *fiber.Ctx) error {
+	var guest dto.Guest
+
+	err := ctx.BodyParser(&guest)
+	if err != nil && len(ctx.Body()) != 0 {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(guest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	guest.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	guest.IPAddress = ctx.IP()
+
+	res, token, err := u.UserUseCase.CreateGuest(guest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusInternalServerError,
+			"failed to create guest",
+		)
+	}
+
+	return ctx.Status(http.StatusCreated).JSON(fiber.Map{
+		"message":       "guest created",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"device_secret": token.DeviceSecret,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) GuestLogin(ctx *fiber.Ctx) error {
+	var guestLogin dto.GuestLogin
+
+	err := ctx.BodyParser(&guestLogin)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(guestLogin)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	guestLogin.UserAgent = ctx.Get(fiber.HeaderUserAgent)
+	guestLogin.IPAddress = ctx.IP()
+
+	res, token, err := u.UserUseCase.GuestLogin(guestLogin)
+	if err != nil {
+		var accountLocked *usecase.AccountLockedError
+		if errors.As(err, &accountLocked) {
+			return accountLockedResponse(ctx, accountLocked)
+		}
+
+		var loginThrottled *usecase.LoginThrottledError
+		if errors.As(err, &loginThrottled) {
+			return u.loginThrottledResponse(ctx, loginThrottled)
+		}
+
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"invalid device secret",
+		)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message":       "user authenticated",
+		"token":         token.Token,
+		"refresh_token": token.RefreshToken,
+		"payload":       res,
+	})
+}
+
+func (u *UserHandler) UpgradeGuest(ctx *fiber.Ctx) error {
+	var upgradeGuest dto.UpgradeGuest
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&upgradeGuest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(upgradeGuest)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	upgradeGuest.UserID = userID
+
+	res, err := u.UserUseCase.UpgradeGuest(upgradeGuest)
+	if err != nil {
+		return guestError(err)
+	}
+
+	if !res.EmailVerified {
+		go u.sendEmailVerification(res.ID)
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "guest upgraded",
+		"payload": res,
+	})
+}
+
+func (u *UserHandler) UpgradeGuestWithIdentity(ctx *fiber.Ctx) error {
+	var oauthCallback dto.OAuthCallback
+
+	userID, err := uuid.Parse(ctx.Locals("userID").(string))
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	err = ctx.BodyParser(&oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"failed to parse request body",
+		)
+	}
+
+	err = u.Validator.Struct(oauthCallback)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			"invalid request body",
+		)
+	}
+
+	oauthCallback.Provider = ctx.Params("provider")
+	oauthCallback.UserID = userID
+
+	res, err := u.UserUseCase.UpgradeGuestWithIdentity(oauthCallback)
+	if err != nil {
+		if strings.Contains(err.Error(), "user is not a guest") {
+			return guestError(err)
+		}
+
+		return oauthError(err, "failed to upgrade guest")
+	}
+
+	return ctx.Status(http.StatusOK).JSON(fiber.Map{
+		"message": "guest upgraded",
+		"payload": res,
+	})
+}
+
+func guestError(err error) error {
+	if strings.Contains(err.Error(), "invalid verification ticket") {
+		return fiber.NewError(
+			http.StatusBadRequest,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "user is not a guest") {
+		return fiber.NewError(
+			http.StatusForbidden,
+			err.Error(),
+		)
+	}
+
+	if strings.Contains(err.Error(), "email or username already registered") {
+		return fiber.NewError(
+			http.StatusConflict,
+			err.Error(),
+		)
+	}
+
+	return fiber.NewError(
+		http.StatusInternalServerError,
+		"failed to upgrade guest",
+	)
+}
@@ -28,8 +28,11 @@ type UserMySQLItf interface {
 	CheckUserID(checkUserID *entity.User) error
 	ChangePassword(user *entity.User) error
 	NewEmailVerification(verification *entity.Verification) error
+	UpdateEmailVerification(verification *entity.Verification) error
 	ValidateEmail(verification *entity.Verification) error
 	GetEmailVerification(verification *entity.Verification) error
+	GetVerificationTicket(verification *entity.Verification) error
+	VerifyUserEmail(user *entity.User) error
 	GetEmail(user *entity.User, userParam dto.ResetPassword) error
 	CheckUsername(user *entity.User) error
 	GetUserInfo(user *entity.User) error
@@ -40,18 +43,50 @@ type UserMySQLItf interface {
 	GetPasswordChangeID(passwordChange *entity.PasswordChange, userParam dto.ResetPassword) error
 	GetPasswordResetCode(passwordResetcode *entity.PasswordResetCode) error
 	GetPasswordChangeValidity(passwordChange *entity.PasswordChange) error
-	GetPasswordResetCodeValidity(passwordChange *entity.PasswordResetCode) error
 	GetPasswordChangeEntry(passwordChange *entity.PasswordChange, userParam dto.ResetPasswordWithID) error
 	CreatePasswordChangeEntry(passwordChange *entity.PasswordChange) error
 	CreatePasswordResetCode(passwordResetCode *entity.PasswordResetCode) error
-	UpdatePasswordResetCode(passwordResetCode *entity.PasswordResetCode) error
+	UsePasswordResetCode(passwordResetCode *entity.PasswordResetCode) error
 	UpdatePasswordChangeEntry(passwordChange *entity.PasswordChange) error
-	UpdateUserInfo(user *entity.User) error
+	UpdateUserInfo(user *entity.User, emailChanged bool) error
 	UpdateUserDetail(userDetail *entity.UserDetail) error
 	UpdateLastActivity(userID uuid.UUID) error
 	CheckReportUser(userReporting *entity.UserReporting) error
 	ReportUser(userReporting *entity.UserReporting) error
 	SoftDelete(user *entity.User) error
+	CreateRefreshToken(refreshToken *entity.RefreshToken) error
+	GetRefreshToken(refreshToken *entity.RefreshToken) error
+	UseRefreshToken(refreshToken *entity.RefreshToken) error
+	RevokeRefreshTokenFamily(familyID uuid.UUID) error
+	RevokeRefreshTokens(userID uuid.UUID) error
+	CreateSession(session *entity.Session) error
+	GetSession(session *entity.Session) error
+	ListSessions(sessions *[]entity.Session, userID uuid.UUID) error
+	UpdateSessionLastSeen(sessionID uuid.UUID) error
+	RevokeSession(session *entity.Session) error
+	RevokeSessions(userID uuid.UUID) error
+	GetRoles(userRoles *[]entity.UserRole, userID uuid.UUID) error
+	CheckDataOwner(data *entity.Data) error
+	GetAccountStatus(user *entity.User) error
+	CheckOpenAppeal(appeal *entity.Appeal) error
+	CreateAppeal(appeal *entity.Appeal) error
+	UpdateTwoFactorSecret(user *entity.User) error
+	EnableTwoFactor(user *entity.User, recoveryCodes *[]entity.RecoveryCode) error
+	DisableTwoFactor(user *entity.User) error
+	ReplaceRecoveryCodes(userID uuid.UUID, recoveryCodes *[]entity.RecoveryCode) error
+	UseRecoveryCode(recoveryCode *entity.RecoveryCode) error
+	RegisterWithIdentity(user *entity.User, userDetail *entity.UserDetail, linkedIdentity *entity.LinkedIdentity) error
+	GetLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error
+	CreateLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error
+	ListLinkedIdentities(linkedIdentities *[]entity.LinkedIdentity, userID uuid.UUID) error
+	DeleteLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error
+	GetLastLoginCode(loginCode *entity.LoginCode) error
+	GetLoginCodeByToken(loginCode *entity.LoginCode) error
+	CreateLoginCode(loginCode *entity.LoginCode) error
+	UseLoginCode(loginCode *entity.LoginCode) error
+	AddCodeAttempt(model any, id uuid.UUID, maxAttempts int) error
+	UpgradeGuest(user *entity.User) error
+	UpgradeGuestWithIdentity(user *entity.User, linkedIdentity *entity.LinkedIdentity) error
 }
 
 type UserMySQL struct {
@@ -222,27 +257,79 @@ func (r *UserMySQL) NewEmailVerification(verification *entity.Verification) erro
 		Error
 }
 
+func (r *UserMySQL) UpdateEmailVerification(verification *entity.Verification) error {
+	result := r.db.Debug().
+		Model(&entity.Verification{}).
+		Where("id = ?", verification.ID).
+		Updates(map[string]any{
+			"success":           false,
+			"code_hash":         verification.CodeHash,
+			"code_salt":         verification.CodeSalt,
+			"attempts":          0,
+			"expires_at":        verification.ExpiresAt,
+			"sent_at":           verification.SentAt,
+			"ticket_hash":       "",
+			"ticket_expires_at": nil,
+		})
+
+	return result.Error
+}
+
 func (r *UserMySQL) ValidateEmail(verification *entity.Verification) error {
+	result := r.db.Debug().
+		Model(&entity.Verification{}).
+		Where("id = ?", verification.ID).
+		Where("success = ?", false).
+		Updates(map[string]any{
+			"success":           true,
+			"ticket_hash":       verification.TicketHash,
+			"ticket_expires_at": verification.TicketExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("invalid code")
+	}
+
+	verification.Success = true
+
+	return nil
+}
+
+func (r *UserMySQL) GetEmailVerification(verification *entity.Verification) error {
 	return r.db.Debug().
-		Model(&verification).
-		Where(
-			entity.Verification{
-				Email: verification.Email,
-				Code:  verification.Code,
-			},
-		).
-		Update("success", true).
+		Where("email = ?", verification.Email).
+		First(verification).
 		Error
 }
 
-func (r *UserMySQL) GetEmailVerification(verification *entity.Verification) error {
+func (r *UserMySQL) GetVerificationTicket(verification *entity.Verification) error {
 	return r.db.Debug().
-		Select("code, success").
 		Where("email = ?", verification.Email).
+		Where("ticket_hash = ?", verification.TicketHash).
 		First(verification).
 		Error
 }
 
+func (r *UserMySQL) VerifyUserEmail(user *entity.User) error {
+	result := r.db.Debug().
+		Model(&entity.User{}).
+		Where("id = ?", user.ID).
+		Where("email_verified_at IS NULL").
+		Update("email_verified_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("email already verified")
+	}
+
+	return nil
+}
+
 func (r *UserMySQL) GetEmail(user *entity.User, userParam dto.ResetPassword) error {
 	return r.db.Debug().
 		Select("email").
@@ -261,7 +348,7 @@ func (r *UserMySQL) GetUserInfo(user *entity.User) error {
 	return r.db.Debug().
 		Model(&user).
 		Preload("UserDetail").
-		Select("users.id, users.email, users.username, users.name, users.created_at, users.updated_at, user_details.*").
+		Select("users.id, users.email, users.username, users.name, users.guest, users.email_verified_at, users.created_at, users.updated_at, user_details.*").
 		Joins("LEFT JOIN user_details ON user_details.user_id = users.id").
 		First(&user).
 		Error
@@ -308,21 +395,11 @@ func (r *UserMySQL) GetPasswordChangeID(passwordChange *entity.PasswordChange, u
 func (r *UserMySQL) GetPasswordResetCode(passwordResetcode *entity.PasswordResetCode) error {
 	return r.db.Debug().
 		Order("created_at desc").
-		Select("password_change_id, code").
 		Where("user_id = ?", passwordResetcode.UserID).
 		First(passwordResetcode).
 		Error
 }
 
-func (r *UserMySQL) GetPasswordResetCodeValidity(passwordChange *entity.PasswordResetCode) error {
-	return r.db.Debug().
-		Order("created_at desc").
-		Find(&passwordChange).
-		Select("created_at").
-		Where("user_id = ?", passwordChange.UserID).
-		Error
-}
-
 func (r *UserMySQL) GetPasswordChangeValidity(passwordChange *entity.PasswordChange) error {
 	return r.db.Debug().
 		Select("id, created_at, success").
@@ -349,12 +426,19 @@ func (r *UserMySQL) CreatePasswordResetCode(passwordResetCode *entity.PasswordRe
 		Error
 }
 
-func (r *UserMySQL) UpdatePasswordResetCode(passwordResetCode *entity.PasswordResetCode) error {
-	return r.db.Debug().
-		Model(&passwordResetCode).
-		Where("password_change_id = ?", passwordResetCode.PasswordChangeID).
-		Delete(passwordResetCode).
-		Error
+func (r *UserMySQL) UsePasswordResetCode(passwordResetCode *entity.PasswordResetCode) error {
+	result := r.db.Debug().
+		Where("id = ?", passwordResetCode.ID).
+		Delete(&entity.PasswordResetCode{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("password reset code already used")
+	}
+
+	return nil
 }
 
 func (r *UserMySQL) UpdatePasswordChangeEntry(passwordChange *entity.PasswordChange) error {
@@ -365,10 +449,18 @@ func (r *UserMySQL) UpdatePasswordChangeEntry(passwordChange *entity.PasswordCha
 		Error
 }
 
-func (r *UserMySQL) UpdateUserInfo(user *entity.User) error {
-	return r.db.Debug().
-		Updates(user).
-		Error
+func (r *UserMySQL) UpdateUserInfo(user *entity.User, emailChanged bool) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Updates(user).
+			Error
+		if err != nil || !emailChanged {
+			return err
+		}
+
+		return tx.Model(user).
+			Update("email_verified_at", nil).
+			Error
+	})
 }
 
 func (r *UserMySQL) UpdateUserDetail(userDetail *entity.UserDetail) error {
@@ -411,6 +503,7 @@ func (r *UserMySQL) CheckReportUser(userReporting *entity.UserReporting) error {
 		Select("id").
 		Where("user_id = ?", userReporting.UserID).
 		Where("reporter_id = ?", userReporting.ReporterID).
+		Where("status IN ?", []string{entity.ReportStatusOpen, entity.ReportStatusTriaged}).
 		Take(userReporting).
 		Error
 }
@@ -426,3 +519,401 @@ func (r *UserMySQL) SoftDelete(user *entity.User) error {
 		Delete(user).
 		Error
 }
+
+func (r *UserMySQL) CreateRefreshToken(refreshToken *entity.RefreshToken) error {
+	return r.db.Debug().
+		Create(refreshToken).
+		Error
+}
+
+func (r *UserMySQL) GetRefreshToken(refreshToken *entity.RefreshToken) error {
+	return r.db.Debug().
+		Where("token_hash = ?", refreshToken.TokenHash).
+		First(refreshToken).
+		Error
+}
+
+func (r *UserMySQL) UseRefreshToken(refreshToken *entity.RefreshToken) error {
+	result := r.db.Debug().
+		Model(&entity.RefreshToken{}).
+		Where("id = ?", refreshToken.ID).
+		Where("used = ?", false).
+		Where("revoked = ?", false).
+		Update("used", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("refresh token reused")
+	}
+
+	refreshToken.Used = true
+
+	return nil
+}
+
+func (r *UserMySQL) RevokeRefreshTokenFamily(familyID uuid.UUID) error {
+	return r.db.Debug().
+		Model(&entity.RefreshToken{}).
+		Where("family_id = ?", familyID).
+		Update("revoked", true).
+		Error
+}
+
+func (r *UserMySQL) RevokeRefreshTokens(userID uuid.UUID) error {
+	return r.db.Debug().
+		Model(&entity.RefreshToken{}).
+		Where("user_id = ?", userID).
+		Where("revoked = ?", false).
+		Update("revoked", true).
+		Error
+}
+
+func (r *UserMySQL) CreateSession(session *entity.Session) error {
+	return r.db.Debug().
+		Create(session).
+		Error
+}
+
+func (r *UserMySQL) GetSession(session *entity.Session) error {
+	return r.db.Debug().
+		First(session, "id = ?", session.ID).
+		Error
+}
+
+func (r *UserMySQL) ListSessions(sessions *[]entity.Session, userID uuid.UUID) error {
+	return r.db.Debug().
+		Where("user_id = ?", userID).
+		Where("revoked = ?", false).
+		Order("last_seen desc").
+		Find(sessions).
+		Error
+}
+
+func (r *UserMySQL) UpdateSessionLastSeen(sessionID uuid.UUID) error {
+	return r.db.Debug().
+		Model(&entity.Session{}).
+		Where("id = ?", sessionID).
+		Update("last_seen", time.Now().UTC()).
+		Error
+}
+
+func (r *UserMySQL) RevokeSession(session *entity.Session) error {
+	result := r.db.Debug().
+		Model(&entity.Session{}).
+		Where("id = ?", session.ID).
+		Where("user_id = ?", session.UserID).
+		Where("revoked = ?", false).
+		Update("revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+func (r *UserMySQL) RevokeSessions(userID uuid.UUID) error {
+	return r.db.Debug().
+		Model(&entity.Session{}).
+		Where("user_id = ?", userID).
+		Where("revoked = ?", false).
+		Update("revoked", true).
+		Error
+}
+
+func (r *UserMySQL) GetRoles(userRoles *[]entity.UserRole, userID uuid.UUID) error {
+	return r.db.Debug().
+		Where("user_id = ?", userID).
+		Find(userRoles).
+		Error
+}
+
+func (r *UserMySQL) CheckDataOwner(data *entity.Data) error {
+	return r.db.Debug().
+		Select("id").
+		Where("id = ?", data.ID).
+		Where("user_id = ?", data.UserID).
+		Take(data).
+		Error
+}
+
+func (r *UserMySQL) GetAccountStatus(user *entity.User) error {
+	return r.db.Debug().
+		Select("id, status, status_reason, status_expires_at, guest, email_verified_at").
+		First(user, "id = ?", user.ID).
+		Error
+}
+
+func (r *UserMySQL) CheckOpenAppeal(appeal *entity.Appeal) error {
+	return r.db.Debug().
+		Select("id").
+		Where("user_id = ?", appeal.UserID).
+		Where("status = ?", entity.AppealStatusOpen).
+		Take(appeal).
+		Error
+}
+
+func (r *UserMySQL) CreateAppeal(appeal *entity.Appeal) error {
+	return r.db.Debug().
+		Create(appeal).
+		Error
+}
+
+func (r *UserMySQL) UpdateTwoFactorSecret(user *entity.User) error {
+	return r.db.Debug().
+		Model(&entity.User{}).
+		Where("id = ?", user.ID).
+		Where("two_factor_enabled = ?", false).
+		Update("two_factor_secret", user.TwoFactorSecret).
+		Error
+}
+
+func (r *UserMySQL) EnableTwoFactor(user *entity.User, recoveryCodes *[]entity.RecoveryCode) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		result := tx.
+			Model(&entity.User{}).
+			Where("id = ?", user.ID).
+			Where("two_factor_enabled = ?", false).
+			Update("two_factor_enabled", true)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			return errors.New("two factor already enabled")
+		}
+
+		return replaceRecoveryCodes(tx, user.ID, recoveryCodes)
+	})
+}
+
+func (r *UserMySQL) DisableTwoFactor(user *entity.User) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&entity.User{}).
+			Where("id = ?", user.ID).
+			Updates(map[string]any{
+				"two_factor_secret":  "",
+				"two_factor_enabled": false,
+			}).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.
+			Where("user_id = ?", user.ID).
+			Delete(&entity.RecoveryCode{}).
+			Error
+	})
+}
+
+func (r *UserMySQL) ReplaceRecoveryCodes(userID uuid.UUID, recoveryCodes *[]entity.RecoveryCode) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, recoveryCodes)
+	})
+}
+
+func (r *UserMySQL) UseRecoveryCode(recoveryCode *entity.RecoveryCode) error {
+	result := r.db.Debug().
+		Model(&entity.RecoveryCode{}).
+		Where("user_id = ?", recoveryCode.UserID).
+		Where("code_hash = ?", recoveryCode.CodeHash).
+		Where("used = ?", false).
+		Limit(1).
+		Update("used", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID, recoveryCodes *[]entity.RecoveryCode) error {
+	err := tx.
+		Where("user_id = ?", userID).
+		Delete(&entity.RecoveryCode{}).
+		Error
+	if err != nil {
+		return err
+	}
+
+	return tx.
+		Create(recoveryCodes).
+		Error
+}
+
+func (r *UserMySQL) RegisterWithIdentity(user *entity.User, userDetail *entity.UserDetail, linkedIdentity *entity.LinkedIdentity) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(user).Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Create(userDetail).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(linkedIdentity).Error
+	})
+}
+
+func (r *UserMySQL) GetLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error {
+	return r.db.Debug().
+		Where("provider = ?", linkedIdentity.Provider).
+		Where("subject = ?", linkedIdentity.Subject).
+		Take(linkedIdentity).
+		Error
+}
+
+func (r *UserMySQL) CreateLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error {
+	return r.db.Debug().
+		Create(linkedIdentity).
+		Error
+}
+
+func (r *UserMySQL) ListLinkedIdentities(linkedIdentities *[]entity.LinkedIdentity, userID uuid.UUID) error {
+	return r.db.Debug().
+		Where("user_id = ?", userID).
+		Order("created_at asc").
+		Find(linkedIdentities).
+		Error
+}
+
+func (r *UserMySQL) DeleteLinkedIdentity(linkedIdentity *entity.LinkedIdentity) error {
+	result := r.db.Debug().
+		Where("id = ?", linkedIdentity.ID).
+		Where("user_id = ?", linkedIdentity.UserID).
+		Delete(&entity.LinkedIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("record not found")
+	}
+
+	return nil
+}
+
+func (r *UserMySQL) GetLastLoginCode(loginCode *entity.LoginCode) error {
+	return r.db.Debug().
+		Where("user_id = ?", loginCode.UserID).
+		Order("created_at desc").
+		Take(loginCode).
+		Error
+}
+
+func (r *UserMySQL) GetLoginCodeByToken(loginCode *entity.LoginCode) error {
+	return r.db.Debug().
+		Where("token_hash = ?", loginCode.TokenHash).
+		Take(loginCode).
+		Error
+}
+
+func (r *UserMySQL) CreateLoginCode(loginCode *entity.LoginCode) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&entity.LoginCode{}).
+			Where("user_id = ?", loginCode.UserID).
+			Where("used = ?", false).
+			Update("used", true).
+			Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(loginCode).Error
+	})
+}
+
+func (r *UserMySQL) UseLoginCode(loginCode *entity.LoginCode) error {
+	result := r.db.Debug().
+		Model(&entity.LoginCode{}).
+		Where("id = ?", loginCode.ID).
+		Where("used = ?", false).
+		Update("used", true)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("login code already used")
+	}
+
+	loginCode.Used = true
+
+	return nil
+}
+
+func (r *UserMySQL) AddCodeAttempt(model any, id uuid.UUID, maxAttempts int) error {
+	result := r.db.Debug().
+		Model(model).
+		Where("id = ?", id).
+		Where("attempts < ?", maxAttempts).
+		Update("attempts", gorm.Expr("attempts + ?", 1))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("too many attempts")
+	}
+
+	return nil
+}
+
+func (r *UserMySQL) UpgradeGuest(user *entity.User) error {
+	return upgradeGuest(r.db.Debug(), user)
+}
+
+func (r *UserMySQL) UpgradeGuestWithIdentity(user *entity.User, linkedIdentity *entity.LinkedIdentity) error {
+	return r.db.Debug().Transaction(func(tx *gorm.DB) error {
+		err := upgradeGuest(tx, user)
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(linkedIdentity).Error
+	})
+}
+
+func upgradeGuest(tx *gorm.DB, user *entity.User) error {
+	updates := map[string]any{
+		"email":              user.Email,
+		"username":           user.Username,
+		"password":           user.Password,
+		"guest":              false,
+		"device_secret_hash": "",
+		"email_verified_at":  user.EmailVerifiedAt,
+	}
+
+	if user.Name != "" {
+		updates["name"] = user.Name
+	}
+
+	result := tx.
+		Model(&entity.User{}).
+		Where("id = ?", user.ID).
+		Where("guest = ?", true).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return errors.New("user is not a guest")
+	}
+
+	return nil
+}
@@ -2,26 +2,62 @@ package usecase
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"math/big"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/estella-studio/atr-backend/internal/app/user/repository"
 	"github.com/estella-studio/atr-backend/internal/domain/dto"
 	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/estella-studio/atr-backend/internal/infra/env"
 	"github.com/estella-studio/atr-backend/internal/infra/jwt"
+	"github.com/estella-studio/atr-backend/internal/infra/oauth"
+	"github.com/estella-studio/atr-backend/internal/infra/otp"
 	redisitf "github.com/estella-studio/atr-backend/internal/infra/redis"
+	"github.com/estella-studio/atr-backend/internal/infra/totp"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 	"golang.org/x/crypto/bcrypt"
 )
 
+type AccountLockedError struct {
+	Status dto.ResponseAccountStatus
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("account %s", e.Status.Status)
+}
+
+type LoginThrottledError struct {
+	RetryAfter  time.Duration
+	LockedUntil *time.Time
+	Email       string
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.LockedUntil != nil {
+		return "login locked"
+	}
+
+	return "login throttled"
+}
+
 type UserUseCaseItf interface {
 	Register(register dto.Register) (dto.ResponseRegister, error)
-	Login(login dto.Login) (dto.ResponseLogin, string, error)
-	RenewToken(renewToken dto.RenewToken) (string, error)
+	Login(login dto.Login) (dto.ResponseLogin, dto.ResponseToken, error)
+	RefreshToken(refreshToken dto.RefreshToken) (dto.ResponseToken, error)
+	Logout(logout dto.Logout) error
+	LogoutAll(userID uuid.UUID) error
+	ListSessions(userID uuid.UUID, currentSessionID uuid.UUID) (*[]dto.ResponseSession, error)
+	RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error
 	CheckUserID(checkUserID *dto.CheckUserID) error
 	CheckFriendRequestExist(CheckFriendRequestExist *dto.CheckFriendRequestExist) (bool, error)
 	CheckFriendRequestFromFriend(friendID uuid.UUID) (bool, error)
@@ -30,26 +66,43 @@ type UserUseCaseItf interface {
 	GetFriendRequestReceived(userID uuid.UUID, offset int, limit int) (*[]dto.ResponseGetFriendRequest, error)
 	AcceptFriendRequest(acceptFriendRequest *dto.AcceptFriendRequest) error
 	GetFriendList(userID uuid.UUID) (*[]dto.ResponseFriendList, error)
-	NewEmailVerification(emailVerification *dto.EmailVerification) error
-	ValidateEmail(validateEmail *dto.ValidateEmail) error
-	GetEmailVerification(validateEmail *dto.EmailVerification) (uint, bool, error)
+	NewEmailVerification(emailVerification *dto.EmailVerification) (string, error)
+	ValidateEmail(validateEmail *dto.ValidateEmail) (dto.ResponseValidateEmail, error)
+	ResendEmailVerification(userID uuid.UUID) (dto.ResponseEmailVerification, error)
+	VerifyEmail(verifyEmail dto.VerifyEmail) error
 	CheckUsername(userName *dto.CheckUsername) error
 	GetUserInfo(userID uuid.UUID) (dto.ResponseGetUserInfo, error)
 	GetUserInfoPublic(userID uuid.UUID) (dto.ResponseGetUserInfoPublic, error)
 	UpdateUserInfo(updateUserInfo dto.UpdateUserInfo, userID uuid.UUID) (dto.ResponseUpdateUserInfo, error)
-	ResetPassword(resetPassword dto.ResetPassword) error
 	ChangePassword(changePassword dto.ChangePassword, userID uuid.UUID) error
 	CreatePasswordChangeEntry(changeID uuid.UUID, userID uuid.UUID) error
-	CreatePasswordResetCode(changeID uuid.UUID, userID uuid.UUID, code uint) error
+	CreatePasswordResetCode(resetPassword dto.ResetPassword) (dto.ResponsePasswordResetCode, error)
 	UpdatePasswordChangeEntry(changeID uuid.UUID, userID uuid.UUID) error
-	UpdatePasswordResetCode(changeID uuid.UUID, userID uuid.UUID, code uint) error
-	GetPasswordResetCode(userID uuid.UUID, code uint) (uuid.UUID, uint, error)
-	GetPasswordResetCodeValidity(userID uuid.UUID) (time.Time, error)
+	CheckPasswordResetCode(checkPasswordResetCode dto.CheckPasswordResetCode) error
+	ResetPasswordWithCode(resetPasswordWithCode dto.ResetPasswordWithCode) error
 	GetPasswordChangeValidity(id uuid.UUID) (bool, time.Time, error)
 	GetPasswordChangeEntry(id uuid.UUID) (uuid.UUID, error)
 	GetUserIDFromEmail(getUserID dto.ResetPassword) (uuid.UUID, error)
 	GetUserIDFromUsername(username string) (uuid.UUID, error)
 	ReportUser(reportUser dto.ReportUser) error
+	Appeal(appeal dto.Appeal) (dto.ResponseAppeal, error)
+	LoginTwoFactor(loginTwoFactor dto.LoginTwoFactor) (dto.ResponseLogin, dto.ResponseToken, error)
+	EnrollTwoFactor(userID uuid.UUID) (dto.ResponseTwoFactorEnrollment, error)
+	ConfirmTwoFactor(twoFactorCode dto.TwoFactorCode) (dto.ResponseRecoveryCodes, error)
+	DisableTwoFactor(disableTwoFactor dto.DisableTwoFactor) error
+	RegenerateRecoveryCodes(twoFactorCode dto.TwoFactorCode) (dto.ResponseRecoveryCodes, error)
+	OAuthProviders() []string
+	OAuthAuthorize(oauthAuthorize dto.OAuthAuthorize) (dto.ResponseOAuthAuthorize, error)
+	OAuthLogin(oauthCallback dto.OAuthCallback) (dto.ResponseLogin, dto.ResponseToken, error)
+	OAuthLink(oauthCallback dto.OAuthCallback) (dto.ResponseLinkedIdentity, error)
+	ListLinkedIdentities(userID uuid.UUID) (*[]dto.ResponseLinkedIdentity, error)
+	UnlinkIdentity(userID uuid.UUID, identityID uuid.UUID) error
+	CreateLoginCode(passwordlessRequest dto.PasswordlessRequest) (dto.ResponseLoginCode, error)
+	PasswordlessLogin(passwordlessLogin dto.PasswordlessLogin) (dto.ResponseLogin, dto.ResponseToken, error)
+	CreateGuest(guest dto.Guest) (dto.ResponseLogin, dto.ResponseToken, error)
+	GuestLogin(guestLogin dto.GuestLogin) (dto.ResponseLogin, dto.ResponseToken, error)
+	UpgradeGuest(upgradeGuest dto.UpgradeGuest) (dto.ResponseLogin, error)
+	UpgradeGuestWithIdentity(oauthCallback dto.OAuthCallback) (dto.ResponseLogin, error)
 	SoftDelete(userID uuid.UUID) error
 }
 
@@ -60,9 +113,28 @@ type UserUseCase struct {
 	redisItf        redisitf.RedisItf
 	redisContext    context.Context
 	redisExpiration int
+	config          *env.Env
+	totp            totp.TOTPItf
+	oauth           oauth.OAuthItf
+	otp             otp.OTPItf
+}
+
+type oauthState struct {
+	Provider     string    `json:"provider"`
+	UserID       uuid.UUID `json:"user_id"`
+	CodeVerifier string    `json:"code_verifier"`
+	Nonce        string    `json:"nonce"`
 }
 
-func NewUserUseCase(userRepo repository.UserMySQLItf, jwt *jwt.JWT, redis *redis.Client, redisItf redisitf.RedisItf, redisExpiration int) UserUseCaseItf {
+type twoFactorChallenge struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Client     string    `json:"client"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+func NewUserUseCase(userRepo repository.UserMySQLItf, jwt *jwt.JWT, redis *redis.Client, redisItf redisitf.RedisItf, redisExpiration int, config *env.Env, totp totp.TOTPItf, oauth oauth.OAuthItf, otp otp.OTPItf) UserUseCaseItf {
 	return &UserUseCase{
 		userRepo:        userRepo,
 		jwt:             jwt,
@@ -70,6 +142,10 @@ func NewUserUseCase(userRepo repository.UserMySQLItf, jwt *jwt.JWT, redis *redis
 		redisItf:        redisItf,
 		redisContext:    context.Background(),
 		redisExpiration: redisExpiration,
+		config:          config,
+		totp:            totp,
+		oauth:           oauth,
+		otp:             otp,
 	}
 }
 
@@ -90,6 +166,14 @@ func (u *UserUseCase) Register(register dto.Register) (dto.ResponseRegister, err
 		Name:     register.Name,
 	}
 
+	if register.VerificationTicket != "" {
+		user.EmailVerifiedAt, err = u.checkVerificationTicket(register.Email, register.VerificationTicket)
+		if err != nil {
+			return dto.ResponseRegister{},
+				err
+		}
+	}
+
 	userDetail := entity.UserDetail{
 		UserID:       user.ID,
 		AcceptFriend: true,
@@ -113,47 +197,247 @@ func (u *UserUseCase) Register(register dto.Register) (dto.ResponseRegister, err
 	return user.ParseToDTOResponseRegister(), nil
 }
 
-func (u *UserUseCase) Login(login dto.Login) (dto.ResponseLogin, string, error) {
+func (u *UserUseCase) Login(login dto.Login) (dto.ResponseLogin, dto.ResponseToken, error) {
 	var user entity.User
 
-	err := u.userRepo.GetUsername(&user, dto.Login{Username: login.Username})
+	err := u.checkLoginThrottle(login)
 	if err != nil {
 		return dto.ResponseLogin{},
-			"",
+			dto.ResponseToken{},
 			err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password))
+	err = u.userRepo.GetUsername(&user, dto.Login{Username: login.Username})
 	if err != nil {
 		return dto.ResponseLogin{},
-			"",
-			err
+			dto.ResponseToken{},
+			u.loginFailed(login, user.Email, err)
 	}
 
-	token, err := u.jwt.GenerateToken(user.ID)
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(login.Password))
 	if err != nil {
 		return dto.ResponseLogin{},
-			"",
+			dto.ResponseToken{},
+			u.loginFailed(login, user.Email, err)
+	}
+
+	u.loginSucceeded(login)
+
+	return u.completeLogin(&user, login)
+}
+
+func (u *UserUseCase) RefreshToken(refreshToken dto.RefreshToken) (dto.ResponseToken, error) {
+	token := entity.RefreshToken{
+		TokenHash: u.jwt.HashRefreshToken(refreshToken.RefreshToken),
+	}
+
+	err := u.userRepo.GetRefreshToken(&token)
+	if err != nil {
+		return dto.ResponseToken{},
+			errors.New("invalid refresh token")
+	}
+
+	if token.Revoked {
+		return dto.ResponseToken{},
+			errors.New("invalid refresh token")
+	}
+
+	if token.Used {
+		err = u.userRepo.RevokeRefreshTokenFamily(token.FamilyID)
+		if err != nil {
+			log.Println(err)
+		}
+
+		return dto.ResponseToken{},
+			errors.New("refresh token reused")
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return dto.ResponseToken{},
+			errors.New("refresh token expired")
+	}
+
+	user := entity.User{
+		ID: token.UserID,
+	}
+
+	err = u.userRepo.GetAccountStatus(&user)
+	if err != nil {
+		return dto.ResponseToken{},
+			errors.New("invalid refresh token")
+	}
+
+	if user.AccountLocked() {
+		return dto.ResponseToken{},
+			&AccountLockedError{Status: user.ParseToDTOResponseAccountStatus()}
+	}
+
+	err = u.userRepo.UseRefreshToken(&token)
+	if err != nil {
+		if strings.Contains(err.Error(), "refresh token reused") {
+			revokeErr := u.userRepo.RevokeRefreshTokenFamily(token.FamilyID)
+			if revokeErr != nil {
+				log.Println(revokeErr)
+			}
+		}
+
+		return dto.ResponseToken{},
 			err
 	}
 
-	_ = u.userRepo.GetUserInfo(&user)
+	client := jwt.ClientGame
 
-	return user.ParseToDTOResponseLogin(), token, nil
+	session := entity.Session{
+		ID: token.FamilyID,
+	}
+
+	err = u.userRepo.GetSession(&session)
+	if err == nil && session.Client != "" {
+		client = session.Client
+	}
+
+	return u.issueToken(token.UserID, token.FamilyID, client)
 }
 
-func (u *UserUseCase) RenewToken(renewToken dto.RenewToken) (string, error) {
-	var user entity.User
+// clientAllowed keeps admin panel tokens to moderators and admins, every
+// account may use the game client.
+func clientAllowed(client string, roles []string) bool {
+	if client != jwt.ClientAdmin {
+		return true
+	}
 
-	err := u.userRepo.CheckUserID(&user)
+	return slices.Contains(roles, entity.RoleModerator) || slices.Contains(roles, entity.RoleAdmin)
+}
+
+func (u *UserUseCase) Logout(logout dto.Logout) error {
+	err := u.jwt.RevokeToken(logout.TokenID, logout.ExpiresAt)
 	if err != nil {
-		return "",
+		return err
+	}
+
+	if logout.SessionID != uuid.Nil {
+		err = u.RevokeSession(logout.UserID, logout.SessionID)
+		if err != nil && !strings.Contains(err.Error(), "record not found") {
+			return err
+		}
+	}
+
+	if logout.RefreshToken == "" {
+		return nil
+	}
+
+	token := entity.RefreshToken{
+		TokenHash: u.jwt.HashRefreshToken(logout.RefreshToken),
+	}
+
+	err = u.userRepo.GetRefreshToken(&token)
+	if err != nil || token.UserID != logout.UserID {
+		return nil
+	}
+
+	return u.userRepo.RevokeRefreshTokenFamily(token.FamilyID)
+}
+
+func (u *UserUseCase) LogoutAll(userID uuid.UUID) error {
+	err := u.jwt.RevokeAllTokens(userID)
+	if err != nil {
+		return err
+	}
+
+	err = u.userRepo.RevokeSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.RevokeRefreshTokens(userID)
+}
+
+func (u *UserUseCase) ListSessions(userID uuid.UUID, currentSessionID uuid.UUID) (*[]dto.ResponseSession, error) {
+	sessions := new([]entity.Session)
+
+	err := u.userRepo.ListSessions(sessions, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseSession, len(*sessions))
+
+	for i, session := range *sessions {
+		res[i] = session.ParseToDTOResponseSession(currentSessionID)
+	}
+
+	return &res, nil
+}
+
+func (u *UserUseCase) RevokeSession(userID uuid.UUID, sessionID uuid.UUID) error {
+	session := entity.Session{
+		ID:     sessionID,
+		UserID: userID,
+	}
+
+	err := u.userRepo.RevokeSession(&session)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.RevokeRefreshTokenFamily(sessionID)
+}
+
+func (u *UserUseCase) roles(userID uuid.UUID) []string {
+	roles := []string{entity.RolePlayer}
+
+	userRoles := new([]entity.UserRole)
+
+	err := u.userRepo.GetRoles(userRoles, userID)
+	if err != nil {
+		log.Println(err)
+
+		return roles
+	}
+
+	for _, userRole := range *userRoles {
+		roles = append(roles, userRole.Role)
+	}
+
+	return roles
+}
+
+func (u *UserUseCase) issueToken(userID uuid.UUID, sessionID uuid.UUID, client string) (dto.ResponseToken, error) {
+	roles := u.roles(userID)
+
+	if !clientAllowed(client, roles) {
+		return dto.ResponseToken{},
+			errors.New("client not allowed")
+	}
+
+	token, err := u.jwt.GenerateToken(userID, sessionID, roles, client)
+	if err != nil {
+		return dto.ResponseToken{},
+			err
+	}
+
+	refreshToken, err := u.jwt.GenerateRefreshToken()
+	if err != nil {
+		return dto.ResponseToken{},
 			err
 	}
 
-	token, err := u.jwt.GenerateToken(user.ID)
+	err = u.userRepo.CreateRefreshToken(&entity.RefreshToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		FamilyID:  sessionID,
+		TokenHash: refreshToken.Hash,
+		ExpiresAt: refreshToken.ExpiresAt,
+	})
+	if err != nil {
+		return dto.ResponseToken{},
+			err
+	}
 
-	return token, err
+	return dto.ResponseToken{
+		Token:        token,
+		RefreshToken: refreshToken.Token,
+	}, nil
 }
 
 func (u *UserUseCase) CheckUserID(checkUserID *dto.CheckUserID) error {
@@ -300,37 +584,178 @@ func (u *UserUseCase) GetFriendList(userID uuid.UUID) (*[]dto.ResponseFriendList
 	return &res, nil
 }
 
-func (u *UserUseCase) NewEmailVerification(emailVerification *dto.EmailVerification) error {
+func (u *UserUseCase) NewEmailVerification(emailVerification *dto.EmailVerification) (string, error) {
+	var user entity.User
+
+	err := u.userRepo.GetUserIDFromEmail(&user, dto.ResetPassword{Email: emailVerification.Email})
+	if err == nil {
+		return "", errors.New("email already registered")
+	}
+
+	return u.sendEmailVerification(emailVerification.Email)
+}
+
+func (u *UserUseCase) sendEmailVerification(email string) (string, error) {
 	verification := entity.Verification{
-		ID:    emailVerification.ID,
-		Email: emailVerification.Email,
-		Code:  emailVerification.Code,
+		Email: email,
 	}
 
-	err := u.userRepo.NewEmailVerification(&verification)
+	err := u.userRepo.GetEmailVerification(&verification)
+	if err == nil && time.Since(verification.SentAt) < time.Second*time.Duration(u.config.AccountRegistrationCodeRetrySeconds) {
+		return "", errors.New("verification code recently sent")
+	}
 
-	return err
+	code, oneTimeCode, err := u.newOneTimeCode(
+		u.config.AccountRegistrationCodeDigitCount,
+		time.Minute*time.Duration(u.config.AccountRegistrationExpiryMinutes),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	verification.OneTimeCode = oneTimeCode
+	verification.SentAt = time.Now().UTC()
+
+	if verification.ID == uuid.Nil {
+		verification.ID = uuid.New()
+
+		err = u.userRepo.NewEmailVerification(&verification)
+	} else {
+		err = u.userRepo.UpdateEmailVerification(&verification)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return code, nil
 }
 
-func (u *UserUseCase) ValidateEmail(validateEmail *dto.ValidateEmail) error {
-	verification := entity.Verification{
-		Email: validateEmail.Email,
-		Code:  validateEmail.Code,
+func (u *UserUseCase) ValidateEmail(validateEmail *dto.ValidateEmail) (dto.ResponseValidateEmail, error) {
+	verification, err := u.verifyEmailCode(validateEmail.Email, validateEmail.Code)
+	if err != nil {
+		return dto.ResponseValidateEmail{}, err
 	}
 
-	err := u.userRepo.ValidateEmail(&verification)
+	ticket, err := u.jwt.GenerateRefreshToken()
+	if err != nil {
+		return dto.ResponseValidateEmail{}, err
+	}
 
-	return err
+	expiresAt := time.Now().Add(time.Minute * time.Duration(u.config.AccountRegistrationExpiryMinutes)).UTC()
+
+	verification.TicketHash = ticket.Hash
+	verification.TicketExpiresAt = &expiresAt
+
+	err = u.userRepo.ValidateEmail(&verification)
+	if err != nil {
+		return dto.ResponseValidateEmail{}, err
+	}
+
+	return dto.ResponseValidateEmail{
+		VerificationTicket: ticket.Token,
+		ExpiresAt:          expiresAt,
+	}, nil
+}
+
+func (u *UserUseCase) ResendEmailVerification(userID uuid.UUID) (dto.ResponseEmailVerification, error) {
+	user := entity.User{
+		ID: userID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseEmailVerification{}, err
+	}
+
+	if !user.EmailPending() {
+		return dto.ResponseEmailVerification{}, errors.New("email already verified")
+	}
+
+	code, err := u.sendEmailVerification(user.Email)
+	if err != nil {
+		return dto.ResponseEmailVerification{}, err
+	}
+
+	return dto.ResponseEmailVerification{
+		Email: user.Email,
+		Code:  code,
+	}, nil
+}
+
+func (u *UserUseCase) VerifyEmail(verifyEmail dto.VerifyEmail) error {
+	user := entity.User{
+		ID: verifyEmail.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return err
+	}
+
+	if !user.EmailPending() {
+		return errors.New("email already verified")
+	}
+
+	verification, err := u.verifyEmailCode(user.Email, verifyEmail.Code)
+	if err != nil {
+		return err
+	}
+
+	err = u.userRepo.ValidateEmail(&verification)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.VerifyUserEmail(&user)
 }
 
-func (u *UserUseCase) GetEmailVerification(validateEmail *dto.EmailVerification) (uint, bool, error) {
+func (u *UserUseCase) verifyEmailCode(email string, code string) (entity.Verification, error) {
 	verification := entity.Verification{
-		Email: validateEmail.Email,
+		Email: email,
 	}
 
 	err := u.userRepo.GetEmailVerification(&verification)
+	if err != nil || verification.Success {
+		return entity.Verification{}, errors.New("invalid code")
+	}
+
+	err = u.verifyOneTimeCode(
+		&entity.Verification{},
+		verification.ID,
+		&verification.OneTimeCode,
+		code,
+		u.config.CodeMaxAttempts,
+	)
+	if err != nil {
+		return entity.Verification{}, err
+	}
+
+	return verification, nil
+}
+
+func (u *UserUseCase) checkVerificationTicket(email string, ticket string) (*time.Time, error) {
+	verification := entity.Verification{
+		Email:      email,
+		TicketHash: u.jwt.HashRefreshToken(ticket),
+	}
+
+	err := u.userRepo.GetVerificationTicket(&verification)
+	if err != nil ||
+		verification.TicketExpiresAt == nil ||
+		time.Now().After(*verification.TicketExpiresAt) {
+		return nil, errors.New("invalid verification ticket")
+	}
 
-	return verification.Code, verification.Success, err
+	verifiedAt := time.Now().UTC()
+
+	return &verifiedAt, nil
+}
+
+func (u *UserUseCase) markEmailVerified(userID uuid.UUID) {
+	err := u.userRepo.VerifyUserEmail(&entity.User{ID: userID})
+	if err != nil && !strings.Contains(err.Error(), "email already verified") {
+		log.Println(err)
+	}
 }
 
 func (u *UserUseCase) CheckUsername(userName *dto.CheckUsername) error {
@@ -422,7 +847,24 @@ func (u *UserUseCase) UpdateUserInfo(updateUserInfo dto.UpdateUserInfo, userID u
 		AcceptFriend: updateUserInfo.AcceptFriend,
 	}
 
-	err := u.userRepo.UpdateUserInfo(&user)
+	current := entity.User{
+		ID: userID,
+	}
+
+	err := u.userRepo.CheckUserID(&current)
+	if err != nil {
+		return dto.ResponseUpdateUserInfo{},
+			err
+	}
+
+	// A new email has to be verified again, guests attach one by upgrading.
+	emailChanged := user.Email != "" && !strings.EqualFold(user.Email, current.Email)
+	if emailChanged && current.Guest {
+		return dto.ResponseUpdateUserInfo{},
+			errors.New("guest account")
+	}
+
+	err = u.userRepo.UpdateUserInfo(&user, emailChanged)
 	if err != nil {
 		return dto.ResponseUpdateUserInfo{},
 			err
@@ -436,16 +878,6 @@ func (u *UserUseCase) UpdateUserInfo(updateUserInfo dto.UpdateUserInfo, userID u
 	return user.ParseToDTOResponseUpdateUserInfo(), nil
 }
 
-func (u *UserUseCase) ResetPassword(resetPassword dto.ResetPassword) error {
-	user := entity.User{
-		Email: resetPassword.Email,
-	}
-
-	err := u.userRepo.GetEmail(&user, dto.ResetPassword{Email: resetPassword.Email})
-
-	return err
-}
-
 func (u *UserUseCase) ChangePassword(changePassword dto.ChangePassword, userID uuid.UUID) error {
 	hashedPassword, err := bcrypt.GenerateFromPassword(
 		[]byte(changePassword.Password),
@@ -461,8 +893,11 @@ func (u *UserUseCase) ChangePassword(changePassword dto.ChangePassword, userID u
 	}
 
 	err = u.userRepo.ChangePassword(&user)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return u.LogoutAll(userID)
 }
 
 func (u *UserUseCase) CreatePasswordChangeEntry(changeID uuid.UUID, userID uuid.UUID) error {
@@ -477,63 +912,161 @@ func (u *UserUseCase) CreatePasswordChangeEntry(changeID uuid.UUID, userID uuid.
 	return err
 }
 
-func (u *UserUseCase) CreatePasswordResetCode(changeID uuid.UUID, userID uuid.UUID, code uint) error {
-	passwordReset := entity.PasswordResetCode{
-		ID:               uuid.New(),
-		PasswordChangeID: changeID,
-		UserID:           userID,
-		Code:             code,
-	}
+func (u *UserUseCase) CreatePasswordResetCode(resetPassword dto.ResetPassword) (dto.ResponsePasswordResetCode, error) {
+	var user entity.User
 
-	err := u.userRepo.CreatePasswordResetCode(&passwordReset)
+	err := u.userRepo.GetUserIDFromEmail(&user, dto.ResetPassword{Email: resetPassword.Email})
+	if err != nil {
+		return dto.ResponsePasswordResetCode{}, err
+	}
 
-	return err
-}
+	lastPasswordResetCode := entity.PasswordResetCode{
+		UserID: user.ID,
+	}
 
-func (u *UserUseCase) UpdatePasswordChangeEntry(changeID uuid.UUID, userID uuid.UUID) error {
-	passwordChange := entity.PasswordChange{
-		ID:      changeID,
-		UserID:  userID,
-		Success: true,
+	err = u.userRepo.GetPasswordResetCode(&lastPasswordResetCode)
+	if err == nil && time.Since(lastPasswordResetCode.CreatedAt) < time.Second*time.Duration(u.config.PasswordChangeCodeRetrySeconds) {
+		return dto.ResponsePasswordResetCode{}, errors.New("password reset code recently sent")
 	}
 
-	err := u.userRepo.UpdatePasswordChangeEntry(&passwordChange)
+	code, oneTimeCode, err := u.newOneTimeCode(
+		u.config.PasswordChangeCodeDigitcount,
+		time.Minute*time.Duration(u.config.PasswordChangeExpiryMinutes),
+	)
+	if err != nil {
+		return dto.ResponsePasswordResetCode{}, err
+	}
 
-	return err
-}
+	changeID := uuid.New()
+
+	err = u.CreatePasswordChangeEntry(changeID, user.ID)
+	if err != nil {
+		return dto.ResponsePasswordResetCode{}, err
+	}
 
-func (u *UserUseCase) UpdatePasswordResetCode(changeID uuid.UUID, userID uuid.UUID, code uint) error {
 	passwordResetCode := entity.PasswordResetCode{
+		ID:               uuid.New(),
 		PasswordChangeID: changeID,
-		UserID:           userID,
-		Code:             code,
+		UserID:           user.ID,
+		OneTimeCode:      oneTimeCode,
+	}
+
+	err = u.userRepo.CreatePasswordResetCode(&passwordResetCode)
+	if err != nil {
+		return dto.ResponsePasswordResetCode{}, err
+	}
+
+	return dto.ResponsePasswordResetCode{
+		ID:    changeID,
+		Email: resetPassword.Email,
+		Code:  code,
+	}, nil
+}
+
+func (u *UserUseCase) UpdatePasswordChangeEntry(changeID uuid.UUID, userID uuid.UUID) error {
+	passwordChange := entity.PasswordChange{
+		ID:      changeID,
+		UserID:  userID,
+		Success: true,
 	}
 
-	err := u.userRepo.UpdatePasswordResetCode(&passwordResetCode)
+	err := u.userRepo.UpdatePasswordChangeEntry(&passwordChange)
+
+	return err
+}
+
+func (u *UserUseCase) CheckPasswordResetCode(checkPasswordResetCode dto.CheckPasswordResetCode) error {
+	_, err := u.verifyPasswordResetCode(checkPasswordResetCode.Email, checkPasswordResetCode.Code)
 
 	return err
 }
 
-func (u *UserUseCase) GetPasswordResetCode(userID uuid.UUID, code uint) (uuid.UUID, uint, error) {
+func (u *UserUseCase) ResetPasswordWithCode(resetPasswordWithCode dto.ResetPasswordWithCode) error {
+	passwordResetCode, err := u.verifyPasswordResetCode(resetPasswordWithCode.Email, resetPasswordWithCode.Code)
+	if err != nil {
+		return err
+	}
+
+	err = u.userRepo.UsePasswordResetCode(&passwordResetCode)
+	if err != nil {
+		return errors.New("invalid code")
+	}
+
+	err = u.ChangePassword(dto.ChangePassword{Password: resetPasswordWithCode.Password}, passwordResetCode.UserID)
+	if err != nil {
+		return err
+	}
+
+	u.markEmailVerified(passwordResetCode.UserID)
+
+	return u.UpdatePasswordChangeEntry(passwordResetCode.PasswordChangeID, passwordResetCode.UserID)
+}
+
+func (u *UserUseCase) verifyPasswordResetCode(email string, code string) (entity.PasswordResetCode, error) {
+	var user entity.User
+
+	err := u.userRepo.GetUserIDFromEmail(&user, dto.ResetPassword{Email: email})
+	if err != nil {
+		return entity.PasswordResetCode{}, errors.New("invalid code")
+	}
+
 	passwordResetCode := entity.PasswordResetCode{
-		UserID: userID,
+		UserID: user.ID,
+	}
+
+	err = u.userRepo.GetPasswordResetCode(&passwordResetCode)
+	if err != nil {
+		return entity.PasswordResetCode{}, errors.New("invalid code")
 	}
 
-	err := u.userRepo.GetPasswordResetCode(
-		&passwordResetCode,
+	err = u.verifyOneTimeCode(
+		&entity.PasswordResetCode{},
+		passwordResetCode.ID,
+		&passwordResetCode.OneTimeCode,
+		code,
+		u.config.CodeMaxAttempts,
 	)
+	if err != nil {
+		return entity.PasswordResetCode{}, err
+	}
 
-	return passwordResetCode.PasswordChangeID, passwordResetCode.Code, err
+	return passwordResetCode, nil
 }
 
-func (u *UserUseCase) GetPasswordResetCodeValidity(userID uuid.UUID) (time.Time, error) {
-	passwordResetCode := entity.PasswordResetCode{
-		UserID: userID,
+func (u *UserUseCase) newOneTimeCode(digits uint, expiry time.Duration) (string, entity.OneTimeCode, error) {
+	code, err := u.otp.Generate(digits)
+	if err != nil {
+		return "", entity.OneTimeCode{}, err
+	}
+
+	return code.Code, entity.OneTimeCode{
+		CodeHash:  code.Hash,
+		CodeSalt:  code.Salt,
+		ExpiresAt: time.Now().Add(expiry).UTC(),
+	}, nil
+}
+
+func (u *UserUseCase) verifyOneTimeCode(model any, id uuid.UUID, oneTimeCode *entity.OneTimeCode, code string, maxAttempts int) error {
+	if time.Now().After(oneTimeCode.ExpiresAt) {
+		return errors.New("invalid code")
+	}
+
+	err := u.userRepo.AddCodeAttempt(model, id, maxAttempts)
+	if err != nil {
+		if strings.Contains(err.Error(), "too many attempts") {
+			return errors.New("invalid code")
+		}
+
+		return err
 	}
 
-	err := u.userRepo.GetPasswordResetCodeValidity(&passwordResetCode)
+	oneTimeCode.Attempts++
 
-	return passwordResetCode.CreatedAt, err
+	if !u.otp.Verify(code, oneTimeCode.CodeSalt, oneTimeCode.CodeHash) {
+		return errors.New("invalid code")
+	}
+
+	return nil
 }
 
 func (u *UserUseCase) GetPasswordChangeValidity(id uuid.UUID) (bool, time.Time, error) {
@@ -591,7 +1124,27 @@ func (u *UserUseCase) ReportUser(reportUser dto.ReportUser) error {
 		return errors.New("user already reported")
 	}
 
+	if reportUser.DataID != nil {
+		data := entity.Data{
+			ID:     *reportUser.DataID,
+			UserID: reportUser.UserID,
+		}
+
+		err = u.userRepo.CheckDataOwner(&data)
+		if err != nil {
+			return errors.New("save data not found")
+		}
+	}
+
 	userReporting.ID = uuid.New()
+	userReporting.Reason = reportUser.Reason
+	userReporting.Details = reportUser.Details
+	userReporting.DataID = reportUser.DataID
+	userReporting.Status = entity.ReportStatusOpen
+
+	if userReporting.Reason == "" {
+		userReporting.Reason = entity.ReportReasonOther
+	}
 
 	err = u.userRepo.ReportUser(&userReporting)
 
@@ -604,6 +1157,1232 @@ func (u *UserUseCase) SoftDelete(userID uuid.UUID) error {
 	}
 
 	err := u.userRepo.SoftDelete(&user)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return u.LogoutAll(userID)
+}
+
+// Appeal goes through the login throttle and fails with the same error for a
+// wrong password and an account that isn't restricted, so it can't be used to
+// guess passwords.
+func (u *UserUseCase) Appeal(appeal dto.Appeal) (dto.ResponseAppeal, error) {
+	var user entity.User
+
+	login := dto.Login{
+		Username:  appeal.Username,
+		IPAddress: appeal.IPAddress,
+	}
+
+	err := u.checkLoginThrottle(login)
+	if err != nil {
+		return dto.ResponseAppeal{}, err
+	}
+
+	err = u.userRepo.GetUsername(&user, dto.Login{Username: appeal.Username})
+	if err != nil {
+		return dto.ResponseAppeal{}, u.loginFailed(login, user.Email, errors.New("invalid credentials"))
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(appeal.Password))
+	if err != nil || !user.AccountLocked() {
+		return dto.ResponseAppeal{}, u.loginFailed(login, user.Email, errors.New("invalid credentials"))
+	}
+
+	u.loginSucceeded(login)
+
+	userAppeal := entity.Appeal{
+		UserID: user.ID,
+	}
+
+	err = u.userRepo.CheckOpenAppeal(&userAppeal)
+	if err == nil {
+		return dto.ResponseAppeal{}, errors.New("appeal already submitted")
+	}
+
+	userAppeal = entity.Appeal{
+		ID:      uuid.New(),
+		UserID:  user.ID,
+		Message: appeal.Message,
+		Status:  entity.AppealStatusOpen,
+	}
+
+	err = u.userRepo.CreateAppeal(&userAppeal)
+	if err != nil {
+		return dto.ResponseAppeal{}, err
+	}
+
+	return userAppeal.ParseToDTOResponseAppeal(), nil
+}
+
+func (u *UserUseCase) checkLoginThrottle(login dto.Login) error {
+	for _, key := range []string{
+		loginLockoutKey("user", strings.ToLower(login.Username)),
+		loginLockoutKey("ip", login.IPAddress),
+	} {
+		ttl, err := u.redis.TTL(u.redisContext, key).Result()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		if ttl > 0 {
+			lockedUntil := time.Now().Add(ttl).UTC()
+
+			return &LoginThrottledError{
+				RetryAfter:  ttl,
+				LockedUntil: &lockedUntil,
+			}
+		}
+	}
+
+	ttl, err := u.redis.TTL(u.redisContext, loginDelayKey(strings.ToLower(login.Username))).Result()
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	if ttl > 0 {
+		return &LoginThrottledError{
+			RetryAfter: ttl,
+		}
+	}
+
+	return nil
+}
+
+// checkAccountLockout rejects every login method, not only passwords, while
+// failed password logins keep the account locked.
+func (u *UserUseCase) checkAccountLockout(username string) error {
+	ttl, err := u.redis.TTL(u.redisContext, loginLockoutKey("user", strings.ToLower(username))).Result()
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	if ttl > 0 {
+		lockedUntil := time.Now().Add(ttl).UTC()
+
+		return &LoginThrottledError{
+			RetryAfter:  ttl,
+			LockedUntil: &lockedUntil,
+		}
+	}
+
+	return nil
+}
+
+func (u *UserUseCase) loginFailed(login dto.Login, email string, loginErr error) error {
+	username := strings.ToLower(login.Username)
+	window := time.Minute * time.Duration(u.config.LoginAttemptWindowMinutes)
+	lockout := time.Minute * time.Duration(u.config.LoginLockoutMinutes)
+
+	ipAttempts, err := u.countLoginAttempt(loginAttemptsKey("ip", login.IPAddress), window)
+	if err != nil {
+		log.Println(err)
+	}
+
+	if u.config.LoginIPMaxAttempts > 0 && ipAttempts >= int64(u.config.LoginIPMaxAttempts) {
+		err = u.redis.Set(u.redisContext, loginLockoutKey("ip", login.IPAddress), 1, lockout).Err()
+		if err != nil {
+			log.Println(err)
+		}
+
+		u.redis.Del(u.redisContext, loginAttemptsKey("ip", login.IPAddress))
+	}
+
+	userAttempts, err := u.countLoginAttempt(loginAttemptsKey("user", username), window)
+	if err != nil {
+		log.Println(err)
+		return loginErr
+	}
+
+	if u.config.LoginMaxAttempts > 0 && userAttempts >= int64(u.config.LoginMaxAttempts) {
+		err = u.redis.Set(u.redisContext, loginLockoutKey("user", username), 1, lockout).Err()
+		if err != nil {
+			log.Println(err)
+			return loginErr
+		}
+
+		u.redis.Del(u.redisContext, loginAttemptsKey("user", username), loginDelayKey(username))
+
+		lockedUntil := time.Now().Add(lockout).UTC()
+
+		return &LoginThrottledError{
+			RetryAfter:  lockout,
+			LockedUntil: &lockedUntil,
+			Email:       email,
+		}
+	}
+
+	shift := min(userAttempts-1, 16)
+
+	delay := time.Millisecond * time.Duration(u.config.LoginDelayMilliseconds) << shift
+	if delay > lockout {
+		delay = lockout
+	}
+
+	if delay > 0 {
+		err = u.redis.Set(u.redisContext, loginDelayKey(username), 1, delay).Err()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	return loginErr
+}
+
+func (u *UserUseCase) loginSucceeded(login dto.Login) {
+	username := strings.ToLower(login.Username)
+
+	err := u.redis.Del(u.redisContext, loginAttemptsKey("user", username), loginDelayKey(username)).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (u *UserUseCase) countLoginAttempt(key string, window time.Duration) (int64, error) {
+	attempts, err := u.redis.Incr(u.redisContext, key).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	if attempts == 1 {
+		err = u.redis.Expire(u.redisContext, key, window).Err()
+		if err != nil {
+			return attempts, err
+		}
+	}
+
+	return attempts, nil
+}
+
+func loginAttemptsKey(scope string, value string) string {
+	return fmt.Sprintf("login:attempts:%s:%s", scope, value)
+}
+
+func loginLockoutKey(scope string, value string) string {
+	return fmt.Sprintf("login:lockout:%s:%s", scope, value)
+}
+
+func loginDelayKey(username string) string {
+	return fmt.Sprintf("login:delay:%s", username)
+}
+
+func (u *UserUseCase) LoginTwoFactor(loginTwoFactor dto.LoginTwoFactor) (dto.ResponseLogin, dto.ResponseToken, error) {
+	var challenge twoFactorChallenge
+
+	hash := u.jwt.HashRefreshToken(loginTwoFactor.ChallengeToken)
+	key := twoFactorChallengeKey(hash)
+
+	result, err := u.redis.Get(u.redisContext, key).Result()
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid challenge token")
+	}
+
+	err = json.Unmarshal([]byte(result), &challenge)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid challenge token")
+	}
+
+	attempts, err := u.countLoginAttempt(twoFactorAttemptsKey(hash), time.Minute*time.Duration(u.config.TOTPChallengeExpiryMinutes))
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	if attempts > u.config.TOTPChallengeMaxAttempts {
+		u.redis.Del(u.redisContext, key, twoFactorAttemptsKey(hash))
+
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid challenge token")
+	}
+
+	user := entity.User{
+		ID: challenge.UserID,
+	}
+
+	err = u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid challenge token")
+	}
+
+	err = u.verifySecondFactor(&user, loginTwoFactor.Code, true)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	deleted, err := u.redis.Del(u.redisContext, key).Result()
+	if err != nil || deleted == 0 {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid challenge token")
+	}
+
+	u.redis.Del(u.redisContext, twoFactorAttemptsKey(hash))
+
+	if user.AccountLocked() {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			&AccountLockedError{Status: user.ParseToDTOResponseAccountStatus()}
+	}
+
+	token, err := u.startSession(user.ID, challenge.Client, challenge.DeviceName, challenge.UserAgent, challenge.IPAddress)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	_ = u.userRepo.GetUserInfo(&user)
+
+	return user.ParseToDTOResponseLogin(), token, nil
+}
+
+func (u *UserUseCase) EnrollTwoFactor(userID uuid.UUID) (dto.ResponseTwoFactorEnrollment, error) {
+	user := entity.User{
+		ID: userID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseTwoFactorEnrollment{}, err
+	}
+
+	if user.TwoFactorEnabled {
+		return dto.ResponseTwoFactorEnrollment{}, errors.New("two factor already enabled")
+	}
+
+	secret, err := u.totp.GenerateSecret()
+	if err != nil {
+		return dto.ResponseTwoFactorEnrollment{}, err
+	}
+
+	user.TwoFactorSecret, err = u.totp.EncryptSecret(secret)
+	if err != nil {
+		return dto.ResponseTwoFactorEnrollment{}, err
+	}
+
+	err = u.userRepo.UpdateTwoFactorSecret(&user)
+	if err != nil {
+		return dto.ResponseTwoFactorEnrollment{}, err
+	}
+
+	return dto.ResponseTwoFactorEnrollment{
+		Secret: secret,
+		URI:    u.totp.URI(secret, user.Username),
+	}, nil
+}
+
+func (u *UserUseCase) ConfirmTwoFactor(twoFactorCode dto.TwoFactorCode) (dto.ResponseRecoveryCodes, error) {
+	user := entity.User{
+		ID: twoFactorCode.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	if user.TwoFactorEnabled {
+		return dto.ResponseRecoveryCodes{}, errors.New("two factor already enabled")
+	}
+
+	if user.TwoFactorSecret == "" {
+		return dto.ResponseRecoveryCodes{}, errors.New("two factor not enrolled")
+	}
+
+	err = u.verifySecondFactor(&user, twoFactorCode.Code, false)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	codes, recoveryCodes, err := u.newRecoveryCodes(user.ID)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	err = u.userRepo.EnableTwoFactor(&user, &recoveryCodes)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	return dto.ResponseRecoveryCodes{
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (u *UserUseCase) DisableTwoFactor(disableTwoFactor dto.DisableTwoFactor) error {
+	user := entity.User{
+		ID: disableTwoFactor.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(disableTwoFactor.Password))
+	if err != nil {
+		return errors.New("invalid password")
+	}
+
+	if !user.TwoFactorEnabled {
+		return errors.New("two factor not enabled")
+	}
+
+	err = u.verifySecondFactor(&user, disableTwoFactor.Code, true)
+	if err != nil {
+		return err
+	}
+
+	return u.userRepo.DisableTwoFactor(&user)
+}
+
+func (u *UserUseCase) RegenerateRecoveryCodes(twoFactorCode dto.TwoFactorCode) (dto.ResponseRecoveryCodes, error) {
+	user := entity.User{
+		ID: twoFactorCode.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	if !user.TwoFactorEnabled {
+		return dto.ResponseRecoveryCodes{}, errors.New("two factor not enabled")
+	}
+
+	err = u.verifySecondFactor(&user, twoFactorCode.Code, false)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	codes, recoveryCodes, err := u.newRecoveryCodes(user.ID)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	err = u.userRepo.ReplaceRecoveryCodes(user.ID, &recoveryCodes)
+	if err != nil {
+		return dto.ResponseRecoveryCodes{}, err
+	}
+
+	return dto.ResponseRecoveryCodes{
+		RecoveryCodes: codes,
+	}, nil
+}
+
+func (u *UserUseCase) startSession(userID uuid.UUID, client string, deviceName string, userAgent string, ipAddress string) (dto.ResponseToken, error) {
+	if client == "" {
+		client = jwt.ClientGame
+	}
+
+	session := entity.Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		DeviceName: deviceName,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		Client:     client,
+		LastSeen:   time.Now().UTC(),
+	}
+
+	err := u.userRepo.CreateSession(&session)
+	if err != nil {
+		return dto.ResponseToken{}, err
+	}
+
+	return u.issueToken(userID, session.ID, client)
+}
+
+func (u *UserUseCase) createTwoFactorChallenge(challenge twoFactorChallenge) (string, error) {
+	token, err := u.jwt.GenerateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(challenge)
+	if err != nil {
+		return "", err
+	}
+
+	expiration := time.Minute * time.Duration(u.config.TOTPChallengeExpiryMinutes)
+
+	err = u.redis.Set(u.redisContext, twoFactorChallengeKey(token.Hash), data, expiration).Err()
+	if err != nil {
+		return "", err
+	}
+
+	return token.Token, nil
+}
+
+func (u *UserUseCase) verifySecondFactor(user *entity.User, code string, allowRecoveryCode bool) error {
+	secret, err := u.totp.DecryptSecret(user.TwoFactorSecret)
+	if err != nil {
+		return err
+	}
+
+	step, valid := u.totp.Validate(secret, strings.TrimSpace(code))
+	if valid {
+		expiration := time.Second * 30 * time.Duration(2*u.config.TOTPSkewSteps+2)
+
+		unused, err := u.redis.SetNX(u.redisContext, fmt.Sprintf("totp:used:%s:%d", user.ID, step), 1, expiration).Result()
+		if err != nil {
+			return err
+		}
+
+		if !unused {
+			return errors.New("invalid code")
+		}
+
+		return nil
+	}
+
+	if !allowRecoveryCode {
+		return errors.New("invalid code")
+	}
+
+	err = u.userRepo.UseRecoveryCode(&entity.RecoveryCode{
+		UserID:   user.ID,
+		CodeHash: u.totp.HashRecoveryCode(code),
+	})
+	if err != nil {
+		return errors.New("invalid code")
+	}
+
+	return nil
+}
+
+func (u *UserUseCase) newRecoveryCodes(userID uuid.UUID) ([]string, []entity.RecoveryCode, error) {
+	codes, err := u.totp.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recoveryCodes := make([]entity.RecoveryCode, len(codes))
+
+	for i, code := range codes {
+		recoveryCodes[i] = entity.RecoveryCode{
+			ID:       uuid.New(),
+			UserID:   userID,
+			CodeHash: u.totp.HashRecoveryCode(code),
+		}
+	}
+
+	return codes, recoveryCodes, nil
+}
+
+func twoFactorChallengeKey(hash string) string {
+	return fmt.Sprintf("2fa:challenge:%s", hash)
+}
+
+func twoFactorAttemptsKey(hash string) string {
+	return fmt.Sprintf("2fa:attempts:%s", hash)
+}
+
+func (u *UserUseCase) completeLogin(user *entity.User, login dto.Login) (dto.ResponseLogin, dto.ResponseToken, error) {
+	if user.AccountLocked() {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			&AccountLockedError{Status: user.ParseToDTOResponseAccountStatus()}
+	}
+
+	err := u.checkAccountLockout(user.Username)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	if !clientAllowed(login.Client, u.roles(user.ID)) {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("client not allowed")
+	}
+
+	if user.TwoFactorEnabled {
+		challengeToken, err := u.createTwoFactorChallenge(twoFactorChallenge{
+			UserID:     user.ID,
+			Client:     login.Client,
+			DeviceName: login.DeviceName,
+			UserAgent:  login.UserAgent,
+			IPAddress:  login.IPAddress,
+		})
+		if err != nil {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				err
+		}
+
+		return dto.ResponseLogin{},
+			dto.ResponseToken{ChallengeToken: challengeToken},
+			nil
+	}
+
+	token, err := u.startSession(user.ID, login.Client, login.DeviceName, login.UserAgent, login.IPAddress)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	_ = u.userRepo.GetUserInfo(user)
+
+	return user.ParseToDTOResponseLogin(), token, nil
+}
+
+func (u *UserUseCase) OAuthProviders() []string {
+	return u.oauth.Providers()
+}
+
+func (u *UserUseCase) OAuthAuthorize(oauthAuthorize dto.OAuthAuthorize) (dto.ResponseOAuthAuthorize, error) {
+	authRequest, err := u.oauth.NewAuthRequest()
+	if err != nil {
+		return dto.ResponseOAuthAuthorize{}, err
+	}
+
+	authURL, err := u.oauth.AuthCodeURL(oauthAuthorize.Provider, authRequest)
+	if err != nil {
+		return dto.ResponseOAuthAuthorize{}, err
+	}
+
+	data, err := json.Marshal(oauthState{
+		Provider:     oauthAuthorize.Provider,
+		UserID:       oauthAuthorize.UserID,
+		CodeVerifier: authRequest.CodeVerifier,
+		Nonce:        authRequest.Nonce,
+	})
+	if err != nil {
+		return dto.ResponseOAuthAuthorize{}, err
+	}
+
+	expiration := time.Minute * time.Duration(u.config.OAuthStateExpiryMinutes)
+
+	err = u.redis.Set(u.redisContext, oauthStateKey(authRequest.State), data, expiration).Err()
+	if err != nil {
+		return dto.ResponseOAuthAuthorize{}, err
+	}
+
+	return dto.ResponseOAuthAuthorize{
+		URL:   authURL,
+		State: authRequest.State,
+	}, nil
+}
+
+func (u *UserUseCase) OAuthLogin(oauthCallback dto.OAuthCallback) (dto.ResponseLogin, dto.ResponseToken, error) {
+	identity, err := u.exchangeOAuthCode(oauthCallback)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	linkedIdentity := entity.LinkedIdentity{
+		Provider: oauthCallback.Provider,
+		Subject:  identity.Subject,
+	}
+
+	err = u.userRepo.GetLinkedIdentity(&linkedIdentity)
+	if err != nil {
+		if !strings.Contains(err.Error(), "record not found") {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				err
+		}
+
+		linkedIdentity, err = u.registerWithIdentity(oauthCallback.Provider, identity)
+		if err != nil {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				err
+		}
+	}
+
+	user := entity.User{
+		ID: linkedIdentity.UserID,
+	}
+
+	err = u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	return u.completeLogin(&user, dto.Login{
+		DeviceName: oauthCallback.DeviceName,
+		UserAgent:  oauthCallback.UserAgent,
+		IPAddress:  oauthCallback.IPAddress,
+	})
+}
+
+func (u *UserUseCase) OAuthLink(oauthCallback dto.OAuthCallback) (dto.ResponseLinkedIdentity, error) {
+	identity, err := u.exchangeOAuthCode(oauthCallback)
+	if err != nil {
+		return dto.ResponseLinkedIdentity{}, err
+	}
+
+	linkedIdentity := entity.LinkedIdentity{
+		ID:       uuid.New(),
+		UserID:   oauthCallback.UserID,
+		Provider: oauthCallback.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	err = u.userRepo.CreateLinkedIdentity(&linkedIdentity)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return dto.ResponseLinkedIdentity{}, errors.New("identity already linked")
+		}
+
+		return dto.ResponseLinkedIdentity{}, err
+	}
+
+	return linkedIdentity.ParseToDTOResponseLinkedIdentity(), nil
+}
+
+func (u *UserUseCase) ListLinkedIdentities(userID uuid.UUID) (*[]dto.ResponseLinkedIdentity, error) {
+	linkedIdentities := new([]entity.LinkedIdentity)
+
+	err := u.userRepo.ListLinkedIdentities(linkedIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.ResponseLinkedIdentity, len(*linkedIdentities))
+
+	for i, linkedIdentity := range *linkedIdentities {
+		res[i] = linkedIdentity.ParseToDTOResponseLinkedIdentity()
+	}
+
+	return &res, nil
+}
+
+func (u *UserUseCase) UnlinkIdentity(userID uuid.UUID, identityID uuid.UUID) error {
+	return u.userRepo.DeleteLinkedIdentity(&entity.LinkedIdentity{
+		ID:     identityID,
+		UserID: userID,
+	})
+}
+
+func (u *UserUseCase) exchangeOAuthCode(oauthCallback dto.OAuthCallback) (oauth.Identity, error) {
+	var state oauthState
+
+	result, err := u.redis.GetDel(u.redisContext, oauthStateKey(oauthCallback.State)).Result()
+	if err != nil {
+		return oauth.Identity{}, errors.New("invalid state")
+	}
+
+	err = json.Unmarshal([]byte(result), &state)
+	if err != nil {
+		return oauth.Identity{}, errors.New("invalid state")
+	}
+
+	if state.Provider != oauthCallback.Provider || state.UserID != oauthCallback.UserID {
+		return oauth.Identity{}, errors.New("invalid state")
+	}
+
+	identity, err := u.oauth.Exchange(oauthCallback.Provider, oauthCallback.Code, oauth.AuthRequest{
+		State:        oauthCallback.State,
+		CodeVerifier: state.CodeVerifier,
+		Nonce:        state.Nonce,
+	})
+	if err != nil {
+		log.Println(err)
+		return oauth.Identity{}, errors.New("identity provider rejected the login")
+	}
+
+	if identity.Subject == "" {
+		return oauth.Identity{}, errors.New("identity provider rejected the login")
+	}
+
+	return identity, nil
+}
+
+func (u *UserUseCase) registerWithIdentity(provider string, identity oauth.Identity) (entity.LinkedIdentity, error) {
+	if identity.Email == "" || !identity.EmailVerified {
+		return entity.LinkedIdentity{}, errors.New("verified email required")
+	}
+
+	existingUser := entity.User{}
+
+	err := u.userRepo.GetUserIDFromEmail(&existingUser, dto.ResetPassword{Email: identity.Email})
+	if err == nil {
+		return entity.LinkedIdentity{}, errors.New("email already registered")
+	}
+
+	username, err := u.generateUsername(identity)
+	if err != nil {
+		return entity.LinkedIdentity{}, err
+	}
+
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return entity.LinkedIdentity{}, err
+	}
+
+	verifiedAt := time.Now().UTC()
+
+	user := entity.User{
+		ID:              uuid.New(),
+		Email:           identity.Email,
+		Username:        username,
+		Password:        hashedPassword,
+		Name:            identityName(identity),
+		EmailVerifiedAt: &verifiedAt,
+	}
+
+	userDetail := entity.UserDetail{
+		UserID:       user.ID,
+		AcceptFriend: true,
+	}
+
+	linkedIdentity := entity.LinkedIdentity{
+		ID:       uuid.New(),
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	err = u.userRepo.RegisterWithIdentity(&user, &userDetail, &linkedIdentity)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return entity.LinkedIdentity{}, errors.New("email already registered")
+		}
+
+		return entity.LinkedIdentity{}, err
+	}
+
+	return linkedIdentity, nil
+}
+
+func (u *UserUseCase) generateUsername(identity oauth.Identity) (string, error) {
+	var base strings.Builder
+
+	source := identity.Name
+	if source == "" {
+		source = strings.Split(identity.Email, "@")[0]
+	}
+
+	for _, r := range strings.ToLower(source) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			base.WriteRune(r)
+		}
+
+		if base.Len() == 14 {
+			break
+		}
+	}
+
+	if base.Len() < 4 {
+		base.Reset()
+		base.WriteString("player")
+	}
+
+	for range 5 {
+		suffix, err := rand.Int(rand.Reader, big.NewInt(1000000))
+		if err != nil {
+			return "", err
+		}
+
+		user := entity.User{
+			Username: fmt.Sprintf("%s%06d", base.String(), suffix.Int64()),
+		}
+
+		err = u.userRepo.CheckUsername(&user)
+		if err != nil {
+			return user.Username, nil
+		}
+	}
+
+	return "", errors.New("failed to generate username")
+}
+
+func identityName(identity oauth.Identity) string {
+	name := []rune(identity.Name)
+	if len(name) > 29 {
+		name = name[:29]
+	}
+
+	return string(name)
+}
+
+func randomPasswordHash() (string, error) {
+	password := make([]byte, 32)
+
+	_, err := rand.Read(password)
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(hex.EncodeToString(password)),
+		bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hashedPassword), nil
+}
+
+func oauthStateKey(state string) string {
+	return fmt.Sprintf("oauth:state:%s", state)
+}
+
+func (u *UserUseCase) CreateLoginCode(passwordlessRequest dto.PasswordlessRequest) (dto.ResponseLoginCode, error) {
+	var user entity.User
+
+	err := u.userRepo.GetUserIDFromEmail(&user, dto.ResetPassword{Email: passwordlessRequest.Email})
+	if err != nil {
+		return dto.ResponseLoginCode{}, err
+	}
+
+	lastLoginCode := entity.LoginCode{
+		UserID: user.ID,
+	}
+
+	err = u.userRepo.GetLastLoginCode(&lastLoginCode)
+	if err == nil && time.Since(lastLoginCode.CreatedAt) < time.Second*time.Duration(u.config.PasswordlessRetrySeconds) {
+		return dto.ResponseLoginCode{}, errors.New("login code recently sent")
+	}
+
+	code, oneTimeCode, err := u.newOneTimeCode(
+		uint(u.config.PasswordlessCodeDigitCount),
+		time.Minute*time.Duration(u.config.PasswordlessExpiryMinutes),
+	)
+	if err != nil {
+		return dto.ResponseLoginCode{}, err
+	}
+
+	token, err := u.jwt.GenerateRefreshToken()
+	if err != nil {
+		return dto.ResponseLoginCode{}, err
+	}
+
+	loginCode := entity.LoginCode{
+		ID:          uuid.New(),
+		UserID:      user.ID,
+		TokenHash:   token.Hash,
+		OneTimeCode: oneTimeCode,
+	}
+
+	err = u.userRepo.CreateLoginCode(&loginCode)
+	if err != nil {
+		return dto.ResponseLoginCode{}, err
+	}
+
+	return dto.ResponseLoginCode{
+		Email:     passwordlessRequest.Email,
+		Code:      code,
+		Token:     token.Token,
+		ExpiresAt: loginCode.ExpiresAt,
+	}, nil
+}
+
+func (u *UserUseCase) PasswordlessLogin(passwordlessLogin dto.PasswordlessLogin) (dto.ResponseLogin, dto.ResponseToken, error) {
+	var loginCode entity.LoginCode
+
+	if passwordlessLogin.Token != "" {
+		loginCode.TokenHash = u.jwt.HashRefreshToken(passwordlessLogin.Token)
+
+		err := u.userRepo.GetLoginCodeByToken(&loginCode)
+		if err != nil {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				errors.New("invalid login code")
+		}
+	} else {
+		var user entity.User
+
+		err := u.userRepo.GetUserIDFromEmail(&user, dto.ResetPassword{Email: passwordlessLogin.Email})
+		if err != nil {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				errors.New("invalid login code")
+		}
+
+		loginCode.UserID = user.ID
+
+		err = u.userRepo.GetLastLoginCode(&loginCode)
+		if err != nil {
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				errors.New("invalid login code")
+		}
+
+		err = u.verifyOneTimeCode(
+			&entity.LoginCode{},
+			loginCode.ID,
+			&loginCode.OneTimeCode,
+			passwordlessLogin.Code,
+			u.config.PasswordlessMaxAttempts,
+		)
+		if err != nil {
+			if strings.Contains(err.Error(), "invalid code") {
+				return dto.ResponseLogin{},
+					dto.ResponseToken{},
+					errors.New("invalid login code")
+			}
+
+			return dto.ResponseLogin{},
+				dto.ResponseToken{},
+				err
+		}
+	}
+
+	if loginCode.Used || time.Now().After(loginCode.ExpiresAt) {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid login code")
+	}
+
+	err := u.userRepo.UseLoginCode(&loginCode)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid login code")
+	}
+
+	u.markEmailVerified(loginCode.UserID)
+
+	user := entity.User{
+		ID: loginCode.UserID,
+	}
+
+	err = u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	return u.completeLogin(&user, dto.Login{
+		DeviceName: passwordlessLogin.DeviceName,
+		UserAgent:  passwordlessLogin.UserAgent,
+		IPAddress:  passwordlessLogin.IPAddress,
+	})
+}
+
+func (u *UserUseCase) CreateGuest(guest dto.Guest) (dto.ResponseLogin, dto.ResponseToken, error) {
+	deviceSecret, err := u.jwt.GenerateRefreshToken()
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	username, err := u.generateUsername(oauth.Identity{Name: "guest"})
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	user := entity.User{
+		ID:               uuid.New(),
+		Username:         username,
+		Password:         hashedPassword,
+		Guest:            true,
+		DeviceSecretHash: deviceSecret.Hash,
+	}
+
+	user.Email = fmt.Sprintf("%s@guest.invalid", user.ID)
+
+	userDetail := entity.UserDetail{
+		UserID:       user.ID,
+		AcceptFriend: true,
+	}
+
+	err = u.userRepo.Register(&user)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	err = u.userRepo.RegisterUserDetail(&userDetail)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	res, token, err := u.completeLogin(&user, dto.Login{
+		DeviceName: guest.DeviceName,
+		UserAgent:  guest.UserAgent,
+		IPAddress:  guest.IPAddress,
+	})
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			err
+	}
+
+	token.DeviceSecret = deviceSecret.Token
+
+	return res, token, nil
+}
+
+func (u *UserUseCase) GuestLogin(guestLogin dto.GuestLogin) (dto.ResponseLogin, dto.ResponseToken, error) {
+	user := entity.User{
+		ID: guestLogin.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid device secret")
+	}
+
+	hash := u.jwt.HashRefreshToken(guestLogin.DeviceSecret)
+
+	if !user.Guest || subtle.ConstantTimeCompare([]byte(hash), []byte(user.DeviceSecretHash)) != 1 {
+		return dto.ResponseLogin{},
+			dto.ResponseToken{},
+			errors.New("invalid device secret")
+	}
+
+	return u.completeLogin(&user, dto.Login{
+		DeviceName: guestLogin.DeviceName,
+		UserAgent:  guestLogin.UserAgent,
+		IPAddress:  guestLogin.IPAddress,
+	})
+}
+
+func (u *UserUseCase) UpgradeGuest(upgradeGuest dto.UpgradeGuest) (dto.ResponseLogin, error) {
+	user := entity.User{
+		ID: upgradeGuest.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	if !user.Guest {
+		return dto.ResponseLogin{}, errors.New("user is not a guest")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword(
+		[]byte(upgradeGuest.Password),
+		bcrypt.DefaultCost)
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	user.Email = upgradeGuest.Email
+	user.Username = upgradeGuest.Username
+	user.Password = string(hashedPassword)
+	user.Name = upgradeGuest.Name
+
+	if upgradeGuest.VerificationTicket != "" {
+		user.EmailVerifiedAt, err = u.checkVerificationTicket(upgradeGuest.Email, upgradeGuest.VerificationTicket)
+		if err != nil {
+			return dto.ResponseLogin{}, err
+		}
+	}
+
+	err = u.userRepo.UpgradeGuest(&user)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return dto.ResponseLogin{}, errors.New("email or username already registered")
+		}
+
+		return dto.ResponseLogin{}, err
+	}
+
+	user = entity.User{
+		ID: upgradeGuest.UserID,
+	}
+
+	_ = u.userRepo.GetUserInfo(&user)
+
+	return user.ParseToDTOResponseLogin(), nil
+}
+
+func (u *UserUseCase) UpgradeGuestWithIdentity(oauthCallback dto.OAuthCallback) (dto.ResponseLogin, error) {
+	user := entity.User{
+		ID: oauthCallback.UserID,
+	}
+
+	err := u.userRepo.CheckUserID(&user)
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	if !user.Guest {
+		return dto.ResponseLogin{}, errors.New("user is not a guest")
+	}
+
+	identity, err := u.exchangeOAuthCode(oauthCallback)
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return dto.ResponseLogin{}, errors.New("verified email required")
+	}
+
+	user.Username, err = u.generateUsername(identity)
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	user.Password, err = randomPasswordHash()
+	if err != nil {
+		return dto.ResponseLogin{}, err
+	}
+
+	verifiedAt := time.Now().UTC()
+
+	user.Email = identity.Email
+	user.Name = identityName(identity)
+	user.EmailVerifiedAt = &verifiedAt
+
+	linkedIdentity := entity.LinkedIdentity{
+		ID:       uuid.New(),
+		UserID:   user.ID,
+		Provider: oauthCallback.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	err = u.userRepo.UpgradeGuestWithIdentity(&user, &linkedIdentity)
+	if err != nil {
+		if strings.Contains(err.Error(), "Duplicate entry") {
+			return dto.ResponseLogin{}, errors.New("email already registered")
+		}
+
+		return dto.ResponseLogin{}, err
+	}
+
+	user = entity.User{
+		ID: oauthCallback.UserID,
+	}
+
+	_ = u.userRepo.GetUserInfo(&user)
+
+	return user.ParseToDTOResponseLogin(), nil
 }
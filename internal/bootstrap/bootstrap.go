@@ -1,13 +1,19 @@
 package bootstrap
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
+	adminhandler "github.com/estella-studio/atr-backend/internal/app/admin/interface/rest"
+	adminrepository "github.com/estella-studio/atr-backend/internal/app/admin/repository"
+	adminusecase "github.com/estella-studio/atr-backend/internal/app/admin/usecase"
 	datahandler "github.com/estella-studio/atr-backend/internal/app/data/interface/rest"
 	datarepository "github.com/estella-studio/atr-backend/internal/app/data/repository"
 	datausecase "github.com/estella-studio/atr-backend/internal/app/data/usecase"
+	jwkshandler "github.com/estella-studio/atr-backend/internal/app/jwks/interface/rest"
 	pinghandler "github.com/estella-studio/atr-backend/internal/app/ping/interface/rest"
 	userhandler "github.com/estella-studio/atr-backend/internal/app/user/interface/rest"
 	userrepository "github.com/estella-studio/atr-backend/internal/app/user/repository"
@@ -16,8 +22,11 @@ import (
 	"github.com/estella-studio/atr-backend/internal/infra/jwt"
 	"github.com/estella-studio/atr-backend/internal/infra/mailer"
 	"github.com/estella-studio/atr-backend/internal/infra/mysql"
+	"github.com/estella-studio/atr-backend/internal/infra/oauth"
+	"github.com/estella-studio/atr-backend/internal/infra/otp"
 	"github.com/estella-studio/atr-backend/internal/infra/redis"
 	"github.com/estella-studio/atr-backend/internal/infra/s3"
+	"github.com/estella-studio/atr-backend/internal/infra/totp"
 	"github.com/estella-studio/atr-backend/internal/middleware"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
@@ -50,7 +59,9 @@ func Start() (*fiber.App, uint, error) {
 
 	log.Println("database connected")
 
-	err = mysql.Migrate(database)
+	totp := totp.NewTOTP(config)
+
+	err = mysql.Migrate(database, totp.EncryptSecret)
 	if err != nil {
 		return nil, 0, err
 	} else {
@@ -61,9 +72,11 @@ func Start() (*fiber.App, uint, error) {
 
 	val := validator.New()
 
-	jwt := jwt.NewJWT(config)
+	jwt := jwt.NewJWT(config, redis)
 
 	mailer := mailer.NewMailer(config)
+	oauth := oauth.NewOAuth(config)
+	otp := otp.NewOTP()
 
 	s3Config := s3.NewS3(config)
 
@@ -75,7 +88,15 @@ func Start() (*fiber.App, uint, error) {
 	)
 
 	app.Use(
-		cache.New(),
+		cache.New(
+			cache.Config{
+				Next: func(ctx *fiber.Ctx) bool {
+					return strings.Contains(
+						string(ctx.Response().Header.Peek(fiber.HeaderCacheControl)),
+						"no-store",
+					)
+				},
+			}),
 		idempotency.New(),
 		cors.New(
 			cors.Config{
@@ -96,18 +117,62 @@ func Start() (*fiber.App, uint, error) {
 		),
 	)
 
+	jwkshandler.NewJWKSHandler(app, jwt)
+
 	v1 := app.Group("/api/v1")
 
 	userRepository := userrepository.NewUserMySQL(database)
 	dataRepository := datarepository.NewDataMySQL(database)
+	adminRepository := adminrepository.NewAdminMySQL(database)
 
-	middleware := middleware.NewMiddleware(*jwt, userRepository)
+	middleware := middleware.NewMiddleware(jwt, userRepository)
 
 	pinghandler.NewPingHandler(v1, middleware)
-	userUseCase := userusecase.NewUserUseCase(userRepository, jwt, redis, redisItf, config.RedisExpiration)
+	userUseCase := userusecase.NewUserUseCase(userRepository, jwt, redis, redisItf, config.RedisExpiration, config, totp, oauth, otp)
 	userhandler.NewUserHandler(v1, val, middleware, userUseCase, config, mailer)
-	dataUseCase := datausecase.NewDataUseCase(dataRepository, jwt)
+	dataUseCase := datausecase.NewDataUseCase(dataRepository, jwt, s3Config, config)
 	datahandler.NewDataHandler(v1, val, middleware, dataUseCase, userUseCase, config, s3Config)
+	adminUseCase := adminusecase.NewAdminUseCase(adminRepository)
+	adminhandler.NewAdminHandler(v1, val, middleware, adminUseCase)
+
+	if config.AdminUsername != "" {
+		err = adminUseCase.BootstrapAdmin(config.AdminUsername)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	go func() {
+		interval := time.Duration(config.DataReconcileIntervalMinutes) * time.Minute
+
+		for range time.Tick(interval) {
+			// Only the instance holding the lease reconciles on this tick.
+			acquired, err := redis.SetNX(context.Background(), "data:reconcile", 1, interval/2).Result()
+			if err != nil {
+				log.Println(err)
+
+				continue
+			}
+
+			if !acquired {
+				continue
+			}
+
+			err = dataUseCase.Reconcile()
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+
+	go func() {
+		for range time.Tick(time.Hour) {
+			err := jwt.Rotate()
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}()
 
 	log.Printf("listening on port %d", config.AppPort)
 
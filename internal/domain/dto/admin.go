@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AssignRole struct {
+	UserID  uuid.UUID `json:"user_id"`
+	ActorID uuid.UUID `json:"actor_id"`
+	Role    string    `json:"role" validate:"required,oneof=moderator admin"`
+}
+
+type ListReports struct {
+	Status string `json:"status" validate:"omitempty,oneof=open triaged resolved dismissed"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+type UpdateReport struct {
+	ID          uuid.UUID `json:"id"`
+	ModeratorID uuid.UUID `json:"moderator_id"`
+	Status      string    `json:"status" validate:"required,oneof=triaged resolved dismissed"`
+	Note        string    `json:"note" validate:"omitempty,max=1024"`
+}
+
+type ModerationAction struct {
+	UserID        uuid.UUID  `json:"user_id"`
+	ModeratorID   uuid.UUID  `json:"moderator_id"`
+	ReportID      *uuid.UUID `json:"report_id"`
+	Action        string     `json:"action" validate:"required,oneof=warn mute suspend ban reinstate"`
+	Reason        string     `json:"reason" validate:"omitempty,max=1024"`
+	DurationHours uint       `json:"duration_hours"`
+}
+
+type ListAppeals struct {
+	Status string `json:"status" validate:"omitempty,oneof=open accepted rejected"`
+	Offset int    `json:"offset"`
+	Limit  int    `json:"limit"`
+}
+
+type UpdateAppeal struct {
+	ID          uuid.UUID `json:"id"`
+	ModeratorID uuid.UUID `json:"moderator_id"`
+	Status      string    `json:"status" validate:"required,oneof=accepted rejected"`
+	Note        string    `json:"note" validate:"omitempty,max=1024"`
+}
+
+type ResponseReport struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	ReporterID     uuid.UUID  `json:"reporter_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	DataID         *uuid.UUID `json:"data_id"`
+	Status         string     `json:"status"`
+	ModeratorID    *uuid.UUID `json:"moderator_id"`
+	ResolutionNote string     `json:"resolution_note"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ResponseModerationAction struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	ModeratorID uuid.UUID  `json:"moderator_id"`
+	ReportID    *uuid.UUID `json:"report_id"`
+	Action      string     `json:"action"`
+	Reason      string     `json:"reason"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type ResponseRoles struct {
+	UserID uuid.UUID `json:"user_id"`
+	Roles  []string  `json:"roles"`
+}
+
+type ResponseRoleAssignment struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	Role      string    `json:"role"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -7,10 +7,16 @@ import (
 )
 
 type Add struct {
-	ID     uuid.UUID `json:"id"`
-	UserID uuid.UUID `json:"user_id"`
-	Type   bool      `json:"type" validate:"required,boolean"`
-	Data   string    `json:"data"`
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Slot        string    `json:"slot" validate:"omitempty,max=64"`
+	Type        bool      `json:"type" validate:"required,boolean"`
+	Data        string    `json:"data"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum" validate:"omitempty,sha256"`
+	ContentType string    `json:"content_type" validate:"max=128"`
+	GameVersion string    `json:"game_version" validate:"max=32"`
+	IfMatch     string    `json:"if_match"`
 }
 
 type Retrieve struct {
@@ -18,24 +24,122 @@ type Retrieve struct {
 	UserID uuid.UUID `json:"user_id"`
 }
 
+type Download struct {
+	ID     uuid.UUID `json:"id" validate:"required"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type Confirm struct {
+	ID     uuid.UUID `json:"id" validate:"required"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type Overwrite struct {
+	ID          uuid.UUID `json:"id" validate:"required"`
+	UserID      uuid.UUID `json:"user_id"`
+	Type        bool      `json:"type" validate:"boolean"`
+	Checksum    string    `json:"checksum" validate:"omitempty,sha256"`
+	ContentType string    `json:"content_type" validate:"max=128"`
+	GameVersion string    `json:"game_version" validate:"max=32"`
+	IfMatch     string    `json:"if_match"`
+}
+
+type Delete struct {
+	ID     uuid.UUID `json:"id" validate:"required"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type Slot struct {
+	UserID uuid.UUID `json:"user_id"`
+	Slot   string    `json:"slot" validate:"required,max=64"`
+}
+
+type Restore struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Slot     string    `json:"slot" validate:"required,max=64"`
+	Revision uint      `json:"revision" validate:"required"`
+	IfMatch  string    `json:"if_match"`
+}
+
+type Prune struct {
+	UserID uuid.UUID `json:"user_id"`
+	Slot   string    `json:"slot" validate:"required,max=64"`
+	Keep   int       `json:"keep" validate:"min=1"`
+}
+
 type List struct {
 	UserID uuid.UUID `json:"user_id"`
 }
 
 type ResponseAdd struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Type      bool      `json:"type"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Slot        string    `json:"slot"`
+	Revision    uint      `json:"revision"`
+	ETag        string    `json:"etag"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	ContentType string    `json:"content_type"`
+	GameVersion string    `json:"game_version"`
+	Type        bool      `json:"type"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type ResponseRetrieve struct {
-	Type bool   `json:"type"`
-	Data string `json:"data"`
+	ETag        string `json:"etag"`
+	Size        int64  `json:"size"`
+	Checksum    string `json:"checksum"`
+	ContentType string `json:"content_type"`
+	GameVersion string `json:"game_version"`
+	Type        bool   `json:"type"`
+	Data        string `json:"data"`
+	Status      string `json:"status"`
 }
 
 type ResponseList struct {
+	ID          uuid.UUID `json:"id"`
+	Slot        string    `json:"slot"`
+	Revision    uint      `json:"revision"`
+	ETag        string    `json:"etag"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	ContentType string    `json:"content_type"`
+	GameVersion string    `json:"game_version"`
+	Type        bool      `json:"type"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ResponseDownload struct {
+	ID          uuid.UUID `json:"id"`
+	ObjectKey   string    `json:"object_key"`
+	Compressed  bool      `json:"compressed"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"content_type"`
+	Status      string    `json:"status"`
+}
+
+type ResponseStatus struct {
+	ID     uuid.UUID `json:"id"`
+	Status string    `json:"status"`
+}
+
+type ResponsePresign struct {
 	ID        uuid.UUID `json:"id"`
-	Type      bool      `json:"type"`
-	CreatedAt time.Time `json:"created_at"`
+	Method    string    `json:"method"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ResponsePrune struct {
+	Slot    string `json:"slot"`
+	Removed int    `json:"removed"`
+}
+
+type ResponseQuota struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	LimitBytes int64 `json:"limit_bytes"`
+	UsedCount  int64 `json:"used_count"`
+	LimitCount int64 `json:"limit_count"`
 }
@@ -7,21 +7,109 @@ import (
 )
 
 type Register struct {
-	ID           uuid.UUID `json:"id"`
-	Email        string    `json:"email" validate:"required,email"`
-	Username     string    `json:"username" validate:"required,min=4,max=20"`
-	Password     string    `json:"password" validate:"required,min=4"`
-	Name         string    `json:"name" validate:"omitempty,min=3,max=29"`
-	ProfileIndex uint      `json:"profile_index" validate:"omitempty"`
+	ID                 uuid.UUID `json:"id"`
+	Email              string    `json:"email" validate:"required,email"`
+	Username           string    `json:"username" validate:"required,min=4,max=20"`
+	Password           string    `json:"password" validate:"required,min=4"`
+	Name               string    `json:"name" validate:"omitempty,min=3,max=29"`
+	ProfileIndex       uint      `json:"profile_index" validate:"omitempty"`
+	VerificationTicket string    `json:"verification_ticket" validate:"omitempty,max=64"`
 }
 
 type Login struct {
-	Username string `json:"username" validate:"required,min=4,max=20"`
-	Password string `json:"password" validate:"required,min=4"`
+	Username   string `json:"username" validate:"required,min=4,max=20"`
+	Password   string `json:"password" validate:"required,min=4"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=128"`
+	Client     string `json:"client" validate:"omitempty,oneof=game admin"`
+	UserAgent  string `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+type LoginTwoFactor struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=32"`
+}
+
+type TwoFactorCode struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code" validate:"required,max=32"`
+}
+
+type DisableTwoFactor struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Password string    `json:"password" validate:"required"`
+	Code     string    `json:"code" validate:"required,max=32"`
+}
+
+type OAuthAuthorize struct {
+	Provider string    `json:"provider"`
+	UserID   uuid.UUID `json:"user_id"`
 }
 
-type RenewToken struct {
-	ID uuid.UUID `json:"id"`
+type OAuthCallback struct {
+	Provider   string    `json:"provider"`
+	UserID     uuid.UUID `json:"user_id"`
+	Code       string    `json:"code" validate:"required"`
+	State      string    `json:"state" validate:"required"`
+	DeviceName string    `json:"device_name" validate:"omitempty,max=128"`
+	UserAgent  string    `json:"-"`
+	IPAddress  string    `json:"-"`
+}
+
+type PasswordlessRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type PasswordlessLogin struct {
+	Email      string `json:"email" validate:"required_without=Token,omitempty,email"`
+	Code       string `json:"code" validate:"required_without=Token,omitempty,numeric,max=12"`
+	Token      string `json:"token" validate:"omitempty,max=64"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=128"`
+	UserAgent  string `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type Guest struct {
+	DeviceName string `json:"device_name" validate:"omitempty,max=128"`
+	UserAgent  string `json:"-"`
+	IPAddress  string `json:"-"`
+}
+
+type GuestLogin struct {
+	UserID       uuid.UUID `json:"user_id" validate:"required"`
+	DeviceSecret string    `json:"device_secret" validate:"required,max=64"`
+	DeviceName   string    `json:"device_name" validate:"omitempty,max=128"`
+	UserAgent    string    `json:"-"`
+	IPAddress    string    `json:"-"`
+}
+
+type UpgradeGuest struct {
+	UserID             uuid.UUID `json:"user_id"`
+	Email              string    `json:"email" validate:"required,email"`
+	Username           string    `json:"username" validate:"required,min=4,max=20"`
+	Password           string    `json:"password" validate:"required,min=4"`
+	Name               string    `json:"name" validate:"omitempty,min=3,max=29"`
+	VerificationTicket string    `json:"verification_ticket" validate:"omitempty,max=64"`
+}
+
+type Logout struct {
+	UserID       uuid.UUID `json:"user_id"`
+	SessionID    uuid.UUID `json:"session_id"`
+	TokenID      string    `json:"token_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+}
+
+type Appeal struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Username  string    `json:"username" validate:"required,min=4,max=20"`
+	Password  string    `json:"password" validate:"required,min=4"`
+	Message   string    `json:"message" validate:"required,max=2048"`
+	IPAddress string    `json:"-"`
 }
 
 type UserDetail struct {
@@ -83,14 +171,17 @@ type UpdateUserInfo struct {
 }
 
 type EmailVerification struct {
-	ID    uuid.UUID `json:"id"`
-	Email string    `json:"email" validate:"required,email"`
-	Code  uint      `json:"code"`
+	Email string `json:"email" validate:"required,email"`
 }
 
 type ValidateEmail struct {
 	Email string `json:"email" validate:"required,email"`
-	Code  uint   `json:"code" validate:"required,min=8"`
+	Code  string `json:"code" validate:"required,numeric,max=12"`
+}
+
+type VerifyEmail struct {
+	UserID uuid.UUID `json:"user_id"`
+	Code   string    `json:"code" validate:"required,numeric,max=12"`
 }
 
 type CheckUsername struct {
@@ -107,14 +198,13 @@ type ResetPasswordWithID struct {
 
 type CheckPasswordResetCode struct {
 	Email string `json:"email" validate:"required,email"`
-	Code  uint   `json:"code" validate:"required,min=8"`
+	Code  string `json:"code" validate:"required,numeric,max=12"`
 }
 
 type ResetPasswordWithCode struct {
-	Email            string    `json:"email" validate:"required,email"`
-	Code             uint      `json:"code" validate:"required"`
-	Password         string    `json:"password" validate:"required,min=4"`
-	PasswordChangeId uuid.UUID `json:"password_change_id"`
+	Email    string `json:"email" validate:"required,email"`
+	Code     string `json:"code" validate:"required,numeric,max=12"`
+	Password string `json:"password" validate:"required,min=4"`
 }
 
 type ChangePassword struct {
@@ -122,32 +212,38 @@ type ChangePassword struct {
 }
 
 type ReportUser struct {
-	ID         uuid.UUID `json:"id"`
-	UserID     uuid.UUID `json:"user_id"`
-	Username   string    `json:"username" validate:"required,min=4,max=20"`
-	ReporterID uuid.UUID `json:"reporter_id"`
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Username   string     `json:"username" validate:"required,min=4,max=20"`
+	ReporterID uuid.UUID  `json:"reporter_id"`
+	Reason     string     `json:"reason" validate:"omitempty,oneof=cheating harassment offensive_content offensive_name spam other"`
+	Details    string     `json:"details" validate:"omitempty,max=1024"`
+	DataID     *uuid.UUID `json:"data_id"`
 }
 
 type ResponseRegister struct {
-	ID         uuid.UUID `json:"id"`
-	Email      string    `json:"email"`
-	Username   string    `json:"username"`
-	Name       string    `json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UserDetail struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Username      string    `json:"username"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserDetail    struct {
 		ProfileIndex uint `json:"profile_index"`
 	} `json:"user_detail"`
 }
 
 type ResponseLogin struct {
-	ID         uuid.UUID `json:"id"`
-	Email      string    `json:"email"`
-	Username   string    `json:"username"`
-	Name       string    `json:"name"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UserDetail struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Guest         bool      `json:"guest"`
+	EmailVerified bool      `json:"email_verified"`
+	Username      string    `json:"username"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserDetail    struct {
 		ProfileIndex uint      `json:"profile_index"`
 		AcceptFriend bool      `json:"accept_friend"`
 		Bio          string    `json:"bio"`
@@ -155,14 +251,94 @@ type ResponseLogin struct {
 	} `json:"user_detail"`
 }
 
-type ResponseGetUserInfo struct {
+type ResponseToken struct {
+	Token          string `json:"token"`
+	RefreshToken   string `json:"refresh_token"`
+	ChallengeToken string `json:"challenge_token,omitempty"`
+	DeviceSecret   string `json:"device_secret,omitempty"`
+}
+
+type ResponseOAuthAuthorize struct {
+	URL   string `json:"url"`
+	State string `json:"state"`
+}
+
+type ResponseLinkedIdentity struct {
+	ID        uuid.UUID `json:"id"`
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ResponseValidateEmail struct {
+	VerificationTicket string    `json:"verification_ticket"`
+	ExpiresAt          time.Time `json:"expires_at"`
+}
+
+type ResponseEmailVerification struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+type ResponsePasswordResetCode struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+	Code  string    `json:"code"`
+}
+
+type ResponseLoginCode struct {
+	Email     string    `json:"email"`
+	Code      string    `json:"code"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type ResponseTwoFactorEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type ResponseRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type ResponseAccountStatus struct {
+	Status    string     `json:"status"`
+	Reason    string     `json:"reason"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ResponseAppeal struct {
+	ID           uuid.UUID  `json:"id"`
+	UserID       uuid.UUID  `json:"user_id"`
+	Message      string     `json:"message"`
+	Status       string     `json:"status"`
+	ModeratorID  *uuid.UUID `json:"moderator_id"`
+	ResponseNote string     `json:"response_note"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type ResponseSession struct {
 	ID         uuid.UUID `json:"id"`
-	Email      string    `json:"email"`
-	Username   string    `json:"username"`
-	Name       string    `json:"name"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
 	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UserDetail struct {
+	LastSeen   time.Time `json:"last_seen"`
+	Current    bool      `json:"current"`
+}
+
+type ResponseGetUserInfo struct {
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	Guest         bool      `json:"guest"`
+	EmailVerified bool      `json:"email_verified"`
+	Username      string    `json:"username"`
+	Name          string    `json:"name"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserDetail    struct {
 		ProfileIndex uint      `json:"profile_index"`
 		AcceptFriend bool      `json:"accept_friend"`
 		Bio          string    `json:"bio"`
@@ -1,40 +1,136 @@
 package entity
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/estella-studio/atr-backend/internal/domain/dto"
 	"github.com/google/uuid"
 )
 
+const (
+	DataStatusPending    = "pending"
+	DataStatusConfirming = "confirming"
+	DataStatusStored     = "stored"
+	DataStatusFailed     = "failed"
+)
+
 type Data struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36)"`
-	Data      string    `json:"data" gorm:"type:varchar(256)"`
-	Type      bool      `json:"type" gorm:"type:boolean"`
-	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID      uuid.UUID `json:"user_id" gorm:"type:char(36);uniqueIndex:idx_data_slot_revision"`
+	Slot        string    `json:"slot" gorm:"type:varchar(64);default:null;uniqueIndex:idx_data_slot_revision"`
+	Revision    uint      `json:"revision" gorm:"type:int unsigned;uniqueIndex:idx_data_slot_revision"`
+	ObjectKey   string    `json:"object_key" gorm:"type:varchar(128)"`
+	Compressed  bool      `json:"compressed" gorm:"type:boolean"`
+	Version     uint      `json:"version" gorm:"type:int unsigned;default:1"`
+	Data        string    `json:"data" gorm:"type:varchar(256)"`
+	Size        int64     `json:"size" gorm:"type:bigint"`
+	Checksum    string    `json:"checksum" gorm:"type:char(64)"`
+	ContentType string    `json:"content_type" gorm:"type:varchar(128)"`
+	GameVersion string    `json:"game_version" gorm:"type:varchar(32)"`
+	Type        bool      `json:"type" gorm:"type:boolean"`
+	Status      string    `json:"status" gorm:"type:varchar(16);default:stored"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type Blob struct {
+	Checksum       string    `json:"checksum" gorm:"type:char(64);primaryKey"`
+	ObjectKey      string    `json:"object_key" gorm:"type:varchar(128);unique"`
+	Compressed     bool      `json:"compressed" gorm:"type:boolean"`
+	Size           int64     `json:"size" gorm:"type:bigint"`
+	ReferenceCount int64     `json:"reference_count" gorm:"type:bigint"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	Uploaded       bool      `json:"-" gorm:"-"`
+}
+
+type DataUsage struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);primaryKey"`
+	UsedBytes int64     `json:"used_bytes" gorm:"type:bigint"`
+	UsedCount int64     `json:"used_count" gorm:"type:bigint"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+func (d *Data) Key() string {
+	if d.ObjectKey == "" {
+		return d.ID.String()
+	}
+
+	return d.ObjectKey
+}
+
+func (d *Data) ETag() string {
+	return fmt.Sprintf(`"%s-%d"`, d.ID, d.Version)
+}
+
+func (d *Data) MatchETag(ifMatch string) bool {
+	etag := d.ETag()
+
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (d *Data) ParseToDTOResponseAdd() dto.ResponseAdd {
 	return dto.ResponseAdd{
-		ID:        d.ID,
-		UserID:    d.UserID,
-		Type:      d.Type,
-		CreatedAt: d.CreatedAt,
+		ID:          d.ID,
+		UserID:      d.UserID,
+		Slot:        d.Slot,
+		Revision:    d.Revision,
+		ETag:        d.ETag(),
+		Size:        d.Size,
+		Checksum:    d.Checksum,
+		ContentType: d.ContentType,
+		GameVersion: d.GameVersion,
+		Type:        d.Type,
+		Status:      d.Status,
+		CreatedAt:   d.CreatedAt,
 	}
 }
 
 func (d *Data) ParseToDTOResponseRetrieve() dto.ResponseRetrieve {
 	return dto.ResponseRetrieve{
-		Type: d.Type,
-		Data: d.Data,
+		ETag:        d.ETag(),
+		Size:        d.Size,
+		Checksum:    d.Checksum,
+		ContentType: d.ContentType,
+		GameVersion: d.GameVersion,
+		Type:        d.Type,
+		Data:        d.Data,
+		Status:      d.Status,
 	}
 }
 
 func (d *Data) ParseToDTOResponseList() dto.ResponseList {
 	return dto.ResponseList{
-		ID:        d.ID,
-		Type:      d.Type,
-		CreatedAt: d.CreatedAt,
+		ID:          d.ID,
+		Slot:        d.Slot,
+		Revision:    d.Revision,
+		ETag:        d.ETag(),
+		Size:        d.Size,
+		Checksum:    d.Checksum,
+		ContentType: d.ContentType,
+		GameVersion: d.GameVersion,
+		Type:        d.Type,
+		Status:      d.Status,
+		CreatedAt:   d.CreatedAt,
+	}
+}
+
+func (d *Data) ParseToDTOResponseDownload() dto.ResponseDownload {
+	return dto.ResponseDownload{
+		ID:          d.ID,
+		ObjectKey:   d.Key(),
+		Compressed:  d.Compressed,
+		Size:        d.Size,
+		ContentType: d.ContentType,
+		Status:      d.Status,
 	}
 }
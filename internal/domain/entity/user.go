@@ -8,12 +8,60 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RolePlayer    = "player"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	RoleActionGrant  = "grant"
+	RoleActionRevoke = "revoke"
+)
+
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusTriaged   = "triaged"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+const ReportReasonOther = "other"
+
+const (
+	ModerationActionWarn      = "warn"
+	ModerationActionMute      = "mute"
+	ModerationActionSuspend   = "suspend"
+	ModerationActionBan       = "ban"
+	ModerationActionReinstate = "reinstate"
+)
+
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusBanned    = "banned"
+)
+
+const (
+	AppealStatusOpen     = "open"
+	AppealStatusAccepted = "accepted"
+	AppealStatusRejected = "rejected"
+)
+
 type User struct {
 	ID                uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
 	Email             string         `json:"email" gorm:"type:nvarchar(256);not null;unique"`
 	Username          string         `json:"username" gorm:"type:nvarchar(64);not null;unique"`
 	Password          string         `json:"password" gorm:"type:text;not null"`
 	Name              string         `json:"name" gorm:"type:nvarchar(128)"`
+	Status            string         `json:"status" gorm:"type:varchar(16);default:active"`
+	StatusReason      string         `json:"status_reason" gorm:"type:nvarchar(1024)"`
+	StatusExpiresAt   *time.Time     `json:"status_expires_at" gorm:"type:timestamp null"`
+	TwoFactorSecret   string         `json:"-" gorm:"type:varchar(128)"`
+	TwoFactorEnabled  bool           `json:"two_factor_enabled" gorm:"type:boolean"`
+	Guest             bool           `json:"guest" gorm:"type:boolean;index"`
+	DeviceSecretHash  string         `json:"-" gorm:"type:char(64)"`
+	EmailVerifiedAt   *time.Time     `json:"email_verified_at" gorm:"type:timestamp null"`
 	CreatedAt         time.Time      `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
 	UpdatedAt         time.Time      `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
 	DeletedAt         gorm.DeletedAt `gorm:"index"`
@@ -24,6 +72,14 @@ type User struct {
 	PasswordChange    []PasswordChange
 	PasswordResetCode []PasswordResetCode
 	UserReporting     []UserReporting
+	RefreshToken      []RefreshToken
+	Session           []Session
+	UserRole          []UserRole
+	ModerationAction  []ModerationAction
+	Appeal            []Appeal
+	RecoveryCode      []RecoveryCode
+	LinkedIdentity    []LinkedIdentity
+	LoginCode         []LoginCode
 }
 
 type UserDetail struct {
@@ -46,12 +102,22 @@ type FriendRequest struct {
 	Accepted bool      `json:"accepted" gorm:"type:boolean"`
 }
 
+type OneTimeCode struct {
+	CodeHash  string    `json:"-" gorm:"type:char(64)"`
+	CodeSalt  string    `json:"-" gorm:"type:char(32)"`
+	Attempts  uint      `json:"attempts" gorm:"type:tinyint unsigned"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamp"`
+}
+
 type Verification struct {
-	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	Email     string    `json:"email" gorm:"type:nvarchar(256);not null;unique"`
-	Code      uint      `json:"code" gorm:"type:varchar(8)"`
-	Success   bool      `json:"success" gorm:"type:boolean"`
-	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	Email           string     `json:"email" gorm:"type:nvarchar(256);not null;unique"`
+	Success         bool       `json:"success" gorm:"type:boolean"`
+	SentAt          time.Time  `json:"sent_at" gorm:"type:timestamp"`
+	TicketHash      string     `json:"-" gorm:"type:char(64)"`
+	TicketExpiresAt *time.Time `json:"ticket_expires_at" gorm:"type:timestamp null"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	OneTimeCode
 }
 
 type PasswordChange struct {
@@ -66,18 +132,146 @@ type PasswordResetCode struct {
 	ID               uuid.UUID      `json:"id" gorm:"type:char(36);primaryKey"`
 	PasswordChangeID uuid.UUID      `json:"change_id" gorm:"type:char(36)"`
 	UserID           uuid.UUID      `json:"user_id" gorm:"type:char(36)"`
-	Code             uint           `json:"code" gorm:"type:varchar(8)"`
 	CreatedAt        time.Time      `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
 	DeletedAt        gorm.DeletedAt `gorm:"index"`
+	OneTimeCode
 }
 
 type UserReporting struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:char(36)"`
+	ReporterID     uuid.UUID  `json:"reporter_id" gorm:"type:char(36)"`
+	Reason         string     `json:"reason" gorm:"type:varchar(32);default:other"`
+	Details        string     `json:"details" gorm:"type:nvarchar(1024)"`
+	DataID         *uuid.UUID `json:"data_id" gorm:"type:char(36)"`
+	Status         string     `json:"status" gorm:"type:varchar(16);default:open;index"`
+	ModeratorID    *uuid.UUID `json:"moderator_id" gorm:"type:char(36)"`
+	ResolutionNote string     `json:"resolution_note" gorm:"type:nvarchar(1024)"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type ModerationAction struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID      uuid.UUID  `json:"user_id" gorm:"type:char(36);index"`
+	ModeratorID uuid.UUID  `json:"moderator_id" gorm:"type:char(36)"`
+	ReportID    *uuid.UUID `json:"report_id" gorm:"type:char(36)"`
+	Action      string     `json:"action" gorm:"type:varchar(16)"`
+	Reason      string     `json:"reason" gorm:"type:nvarchar(1024)"`
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"type:timestamp null"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type RefreshToken struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	FamilyID  uuid.UUID `json:"family_id" gorm:"type:char(36);index"`
+	TokenHash string    `json:"token_hash" gorm:"type:char(64);not null;unique"`
+	Used      bool      `json:"used" gorm:"type:boolean"`
+	Revoked   bool      `json:"revoked" gorm:"type:boolean"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamp"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type Session struct {
 	ID         uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
-	UserID     uuid.UUID `json:"user_id" gorm:"type:char(36)"`
-	ReporterID uuid.UUID `json:"reporter_id" gorm:"type:char(36)"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	DeviceName string    `json:"device_name" gorm:"type:nvarchar(128)"`
+	UserAgent  string    `json:"user_agent" gorm:"type:varchar(512)"`
+	IPAddress  string    `json:"ip_address" gorm:"type:varchar(64)"`
+	Client     string    `json:"client" gorm:"type:varchar(16);default:'game'"`
+	Revoked    bool      `json:"revoked" gorm:"type:boolean"`
+	LastSeen   time.Time `json:"last_seen" gorm:"type:timestamp"`
 	CreatedAt  time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
 }
 
+type RecoveryCode struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	CodeHash  string    `json:"code_hash" gorm:"type:char(64);not null;index"`
+	Used      bool      `json:"used" gorm:"type:boolean"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type LinkedIdentity struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	Provider  string    `json:"provider" gorm:"type:varchar(32);not null;uniqueIndex:idx_provider_subject"`
+	Subject   string    `json:"subject" gorm:"type:varchar(255);not null;uniqueIndex:idx_provider_subject"`
+	Email     string    `json:"email" gorm:"type:nvarchar(256)"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type LoginCode struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	TokenHash string    `json:"token_hash" gorm:"type:char(64);not null;unique"`
+	Used      bool      `json:"used" gorm:"type:boolean"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	OneTimeCode
+}
+
+type UserRole struct {
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);primaryKey"`
+	Role      string    `json:"role" gorm:"type:varchar(32);primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type RoleAssignment struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	ActorID   uuid.UUID `json:"actor_id" gorm:"type:char(36)"`
+	Role      string    `json:"role" gorm:"type:varchar(32)"`
+	Action    string    `json:"action" gorm:"type:varchar(16)"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+type Appeal struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primaryKey"`
+	UserID       uuid.UUID  `json:"user_id" gorm:"type:char(36);index"`
+	Message      string     `json:"message" gorm:"type:nvarchar(2048)"`
+	Status       string     `json:"status" gorm:"type:varchar(16);default:open;index"`
+	ModeratorID  *uuid.UUID `json:"moderator_id" gorm:"type:char(36)"`
+	ResponseNote string     `json:"response_note" gorm:"type:nvarchar(1024)"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+// RoleRank returns the rank of the highest of the given roles, a player has rank 0.
+func RoleRank(userRoles []UserRole) int {
+	rank := 0
+
+	for _, userRole := range userRoles {
+		switch userRole.Role {
+		case RoleModerator:
+			rank = max(rank, 1)
+		case RoleAdmin:
+			rank = max(rank, 2)
+		}
+	}
+
+	return rank
+}
+
+func (u *User) AccountLocked() bool {
+	switch u.Status {
+	case UserStatusBanned:
+		return true
+	case UserStatusSuspended:
+		return u.StatusExpiresAt == nil || time.Now().Before(*u.StatusExpiresAt)
+	}
+
+	return false
+}
+
+func (u *User) ParseToDTOResponseAccountStatus() dto.ResponseAccountStatus {
+	return dto.ResponseAccountStatus{
+		Status:    u.Status,
+		Reason:    u.StatusReason,
+		ExpiresAt: u.StatusExpiresAt,
+	}
+}
+
 func (u *User) ParseToDTOResponseRegister() dto.ResponseRegister {
 	var responseRegister dto.ResponseRegister
 
@@ -85,6 +279,7 @@ func (u *User) ParseToDTOResponseRegister() dto.ResponseRegister {
 	responseRegister.Email = u.Email
 	responseRegister.Username = u.Username
 	responseRegister.Name = u.Name
+	responseRegister.EmailVerified = u.EmailVerifiedAt != nil
 	responseRegister.CreatedAt = u.CreatedAt
 	responseRegister.UpdatedAt = u.UpdatedAt
 	responseRegister.UserDetail.ProfileIndex = u.UserDetail.ProfileIndex
@@ -92,11 +287,25 @@ func (u *User) ParseToDTOResponseRegister() dto.ResponseRegister {
 	return responseRegister
 }
 
+func (u *User) EmailPending() bool {
+	return !u.Guest && u.EmailVerifiedAt == nil
+}
+
+func (u *User) PublicEmail() string {
+	if u.Guest {
+		return ""
+	}
+
+	return u.Email
+}
+
 func (u *User) ParseToDTOResponseLogin() dto.ResponseLogin {
 	var responseLogin dto.ResponseLogin
 
 	responseLogin.ID = u.ID
-	responseLogin.Email = u.Email
+	responseLogin.Email = u.PublicEmail()
+	responseLogin.Guest = u.Guest
+	responseLogin.EmailVerified = u.EmailVerifiedAt != nil
 	responseLogin.Username = u.Username
 	responseLogin.Name = u.Name
 	responseLogin.CreatedAt = u.CreatedAt
@@ -113,7 +322,9 @@ func (u *User) ParseToDTOResponseGetUserInfo() dto.ResponseGetUserInfo {
 	var responseGetUserInfo dto.ResponseGetUserInfo
 
 	responseGetUserInfo.ID = u.ID
-	responseGetUserInfo.Email = u.Email
+	responseGetUserInfo.Email = u.PublicEmail()
+	responseGetUserInfo.Guest = u.Guest
+	responseGetUserInfo.EmailVerified = u.EmailVerifiedAt != nil
 	responseGetUserInfo.Username = u.Username
 	responseGetUserInfo.Name = u.Name
 	responseGetUserInfo.CreatedAt = u.CreatedAt
@@ -169,3 +380,77 @@ func (u *User) ParseToDTOResponseGetFriendList() dto.ResponseFriendList {
 		Name:     u.Name,
 	}
 }
+
+func (s *Session) ParseToDTOResponseSession(currentSessionID uuid.UUID) dto.ResponseSession {
+	return dto.ResponseSession{
+		ID:         s.ID,
+		DeviceName: s.DeviceName,
+		UserAgent:  s.UserAgent,
+		IPAddress:  s.IPAddress,
+		CreatedAt:  s.CreatedAt,
+		LastSeen:   s.LastSeen,
+		Current:    s.ID == currentSessionID,
+	}
+}
+
+func (ur *UserReporting) ParseToDTOResponseReport() dto.ResponseReport {
+	return dto.ResponseReport{
+		ID:             ur.ID,
+		UserID:         ur.UserID,
+		ReporterID:     ur.ReporterID,
+		Reason:         ur.Reason,
+		Details:        ur.Details,
+		DataID:         ur.DataID,
+		Status:         ur.Status,
+		ModeratorID:    ur.ModeratorID,
+		ResolutionNote: ur.ResolutionNote,
+		CreatedAt:      ur.CreatedAt,
+		UpdatedAt:      ur.UpdatedAt,
+	}
+}
+
+func (ma *ModerationAction) ParseToDTOResponseModerationAction() dto.ResponseModerationAction {
+	return dto.ResponseModerationAction{
+		ID:          ma.ID,
+		UserID:      ma.UserID,
+		ModeratorID: ma.ModeratorID,
+		ReportID:    ma.ReportID,
+		Action:      ma.Action,
+		Reason:      ma.Reason,
+		ExpiresAt:   ma.ExpiresAt,
+		CreatedAt:   ma.CreatedAt,
+	}
+}
+
+func (ra *RoleAssignment) ParseToDTOResponseRoleAssignment() dto.ResponseRoleAssignment {
+	return dto.ResponseRoleAssignment{
+		ID:        ra.ID,
+		UserID:    ra.UserID,
+		ActorID:   ra.ActorID,
+		Role:      ra.Role,
+		Action:    ra.Action,
+		CreatedAt: ra.CreatedAt,
+	}
+}
+
+func (a *Appeal) ParseToDTOResponseAppeal() dto.ResponseAppeal {
+	return dto.ResponseAppeal{
+		ID:           a.ID,
+		UserID:       a.UserID,
+		Message:      a.Message,
+		Status:       a.Status,
+		ModeratorID:  a.ModeratorID,
+		ResponseNote: a.ResponseNote,
+		CreatedAt:    a.CreatedAt,
+		UpdatedAt:    a.UpdatedAt,
+	}
+}
+
+func (l *LinkedIdentity) ParseToDTOResponseLinkedIdentity() dto.ResponseLinkedIdentity {
+	return dto.ResponseLinkedIdentity{
+		ID:        l.ID,
+		Provider:  l.Provider,
+		Email:     l.Email,
+		CreatedAt: l.CreatedAt,
+	}
+}
@@ -1,6 +1,11 @@
 package env
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
 	"github.com/caarlos0/env/v11"
 	"github.com/joho/godotenv"
 )
@@ -10,9 +15,12 @@ type Env struct {
 	LimiterExpirationMinutes            int    `env:"LIMITER_EXPIRATION_MINUTES"`
 	BodyLimit                           int    `env:"BODY_LIMIT_MB"`
 	AccountRegistrationCodeDigitCount   uint   `env:"ACCOUNT_REGISTRATION_CODE_DIGIT_COUNT"`
+	AccountRegistrationExpiryMinutes    int    `env:"ACCOUNT_REGISTRATION_EXPIRY_MINUTES"`
+	AccountRegistrationCodeRetrySeconds int    `env:"ACCOUNT_REGISTRATION_CODE_RETRY_SECONDS"`
 	PasswordChangeCodeDigitcount        uint   `env:"PASSWORD_CHANGE_CODE_DIGIT_COUNT"`
 	PasswordChangeExpiryMinutes         int    `env:"PASSWORD_CHANGE_EXPIRY_MINUTES"`
 	PasswordChangeCodeRetrySeconds      int    `env:"PASSWORD_CHANGE_CODE_RETRY_SECONDS"`
+	CodeMaxAttempts                     int    `env:"CODE_MAX_ATTEMPTS"`
 	AppPort                             uint   `env:"APP_PORT"`
 	DBName                              string `env:"DB_NAME"`
 	DBUsername                          string `env:"DB_USERNAME"`
@@ -25,13 +33,61 @@ type Env struct {
 	RedisPassword                       string `env:"REDIS_PASSWORD"`
 	RedisDatabase                       int    `env:"REDIS_DATABASE"`
 	RedisExpiration                     int    `env:"REDIS_EXPIRATION"`
+	S3Driver                            string `env:"S3_DRIVER"`
 	S3BucketName                        string `env:"S3_BUCKET_NAME"`
 	S3AccountID                         string `env:"S3_ACCOUNT_ID"`
 	S3AccessKeyID                       string `env:"S3_ACCESS_KEY_ID"`
 	S3AccessKeySecret                   string `env:"S3_ACCESS_KEY_SECRET"`
+	S3Endpoint                          string `env:"S3_ENDPOINT"`
+	S3Region                            string `env:"S3_REGION"`
+	S3UsePathStyle                      bool   `env:"S3_USE_PATH_STYLE"`
+	S3LocalDirectory                    string `env:"S3_LOCAL_DIRECTORY"`
 	S3BucketURLPrefix                   string `env:"S3_BUCKET_URL_PREFIX"`
+	S3PresignExpiryMinutes              int    `env:"S3_PRESIGN_EXPIRY_MINUTES" envDefault:"15"`
+	S3UploadRetryCount                  int    `env:"S3_UPLOAD_RETRY_COUNT"`
+	S3UploadRetryBackoffMilliseconds    int    `env:"S3_UPLOAD_RETRY_BACKOFF_MILLISECONDS"`
+	DataPendingExpiryMinutes            int    `env:"DATA_PENDING_EXPIRY_MINUTES" envDefault:"60"`
+	DataQuotaMB                         int64  `env:"DATA_QUOTA_MB"`
+	DataQuotaCount                      int64  `env:"DATA_QUOTA_COUNT"`
+	DataReconcileIntervalMinutes        int    `env:"DATA_RECONCILE_INTERVAL_MINUTES" envDefault:"10"`
+	AdminUsername                       string `env:"ADMIN_USERNAME"`
+	LoginMaxAttempts                    int    `env:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts                  int    `env:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginAttemptWindowMinutes           int    `env:"LOGIN_ATTEMPT_WINDOW_MINUTES"`
+	LoginLockoutMinutes                 int    `env:"LOGIN_LOCKOUT_MINUTES"`
+	LoginDelayMilliseconds              int    `env:"LOGIN_DELAY_MILLISECONDS"`
+	TOTPIssuer                          string `env:"TOTP_ISSUER"`
+	TOTPEncryptionKey                   string `env:"TOTP_ENCRYPTION_KEY"`
+	TOTPSkewSteps                       int64  `env:"TOTP_SKEW_STEPS"`
+	TOTPRecoveryCodeCount               int    `env:"TOTP_RECOVERY_CODE_COUNT"`
+	TOTPChallengeExpiryMinutes          int    `env:"TOTP_CHALLENGE_EXPIRY_MINUTES" envDefault:"5"`
+	TOTPChallengeMaxAttempts            int64  `env:"TOTP_CHALLENGE_MAX_ATTEMPTS" envDefault:"5"`
+	OAuthRedirectURL                    string `env:"OAUTH_REDIRECT_URL"`
+	OAuthStateExpiryMinutes             int    `env:"OAUTH_STATE_EXPIRY_MINUTES"`
+	OAuthGoogleClientID                 string `env:"OAUTH_GOOGLE_CLIENT_ID"`
+	OAuthGoogleClientSecret             string `env:"OAUTH_GOOGLE_CLIENT_SECRET"`
+	OAuthDiscordClientID                string `env:"OAUTH_DISCORD_CLIENT_ID"`
+	OAuthDiscordClientSecret            string `env:"OAUTH_DISCORD_CLIENT_SECRET"`
+	OAuthOIDCIssuer                     string `env:"OAUTH_OIDC_ISSUER"`
+	OAuthOIDCClientID                   string `env:"OAUTH_OIDC_CLIENT_ID"`
+	OAuthOIDCClientSecret               string `env:"OAUTH_OIDC_CLIENT_SECRET"`
+	PasswordlessCodeDigitCount          int    `env:"PASSWORDLESS_CODE_DIGIT_COUNT"`
+	PasswordlessExpiryMinutes           int    `env:"PASSWORDLESS_EXPIRY_MINUTES"`
+	PasswordlessRetrySeconds            int    `env:"PASSWORDLESS_RETRY_SECONDS"`
+	PasswordlessMaxAttempts             int    `env:"PASSWORDLESS_MAX_ATTEMPTS"`
+	PasswordlessLinkURL                 string `env:"PASSWORDLESS_LINK_URL"`
 	JWTSecretKey                        string `env:"JWT_SECRET_KEY"`
+	JWTIssuer                           string `env:"JWT_ISSUER"`
+	JWTAudience                         string `env:"JWT_AUDIENCE" envDefault:"atr-game"`
+	JWTAdminAudience                    string `env:"JWT_ADMIN_AUDIENCE" envDefault:"atr-admin"`
+	JWTServiceAudience                  string `env:"JWT_SERVICE_AUDIENCE" envDefault:"atr-service"`
+	JWTClockSkewSeconds                 int    `env:"JWT_CLOCK_SKEW_SECONDS"`
+	JWTAlgorithm                        string `env:"JWT_ALGORITHM"`
+	JWTHS512CompatibilityUntil          string `env:"JWT_HS512_COMPATIBILITY_UNTIL"`
+	JWTKeyRotationDays                  int    `env:"JWT_KEY_ROTATION_DAYS"`
+	JWTExpiredMinutes                   uint   `env:"JWT_EXPIRED_MINUTES"`
 	JWTExpiredDays                      uint   `env:"JWT_EXPIRED_DAYS"`
+	JWTRefreshExpiredDays               uint   `env:"JWT_REFRESH_EXPIRED_DAYS"`
 	EmailFrom                           string `env:"EMAIL_FROM"`
 	SMTPServer                          string `env:"SMTP_SERVER"`
 	SMTPPort                            int    `env:"SMTP_PORT"`
@@ -62,5 +118,76 @@ func New() (*Env, error) {
 		return nil, err
 	}
 
+	if envParsed.JWTExpiredMinutes == 0 && envParsed.JWTExpiredDays > 0 {
+		log.Println("JWT_EXPIRED_DAYS is deprecated, use JWT_EXPIRED_MINUTES")
+
+		envParsed.JWTExpiredMinutes = envParsed.JWTExpiredDays * 24 * 60
+	}
+
+	err = envParsed.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return envParsed, nil
 }
+
+func (e *Env) validate() error {
+	if e.TOTPEncryptionKey == "" {
+		return errors.New("TOTP_ENCRYPTION_KEY must be set")
+	}
+
+	if e.TOTPChallengeExpiryMinutes <= 0 {
+		return errors.New("TOTP_CHALLENGE_EXPIRY_MINUTES must be greater than zero")
+	}
+
+	if e.TOTPChallengeMaxAttempts <= 0 {
+		return errors.New("TOTP_CHALLENGE_MAX_ATTEMPTS must be greater than zero")
+	}
+
+	switch e.S3Driver {
+	case "", "r2", "s3", "local":
+	default:
+		return fmt.Errorf("unknown S3_DRIVER %q", e.S3Driver)
+	}
+
+	if e.S3PresignExpiryMinutes <= 0 {
+		return errors.New("S3_PRESIGN_EXPIRY_MINUTES must be greater than zero")
+	}
+
+	if e.DataPendingExpiryMinutes <= e.S3PresignExpiryMinutes {
+		return errors.New("DATA_PENDING_EXPIRY_MINUTES must be greater than S3_PRESIGN_EXPIRY_MINUTES")
+	}
+
+	if e.DataReconcileIntervalMinutes <= 0 {
+		return errors.New("DATA_RECONCILE_INTERVAL_MINUTES must be greater than zero")
+	}
+
+	if e.JWTAudience == e.JWTAdminAudience || e.JWTAudience == e.JWTServiceAudience || e.JWTAdminAudience == e.JWTServiceAudience {
+		return errors.New("JWT_AUDIENCE, JWT_ADMIN_AUDIENCE and JWT_SERVICE_AUDIENCE must differ")
+	}
+
+	switch e.JWTAlgorithm {
+	case "", "HS512", "RS256", "EdDSA":
+	default:
+		return fmt.Errorf("unknown JWT_ALGORITHM %q", e.JWTAlgorithm)
+	}
+
+	if e.JWTHS512CompatibilityUntil != "" {
+		_, err := time.Parse(time.RFC3339, e.JWTHS512CompatibilityUntil)
+		if err != nil {
+			return fmt.Errorf("JWT_HS512_COMPATIBILITY_UNTIL must be an RFC 3339 time: %w", err)
+		}
+
+	}
+
+	if e.JWTSecretKey == "" {
+		return errors.New("JWT_SECRET_KEY must be set")
+	}
+
+	if e.JWTExpiredMinutes == 0 {
+		return errors.New("JWT_EXPIRED_MINUTES must be greater than zero")
+	}
+
+	return nil
+}
@@ -1,48 +1,159 @@
 package jwt
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/estella-studio/atr-backend/internal/infra/env"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	ClientGame    = "game"
+	ClientAdmin   = "admin"
+	ClientService = "service"
 )
 
 type JWTItf interface {
-	GenerateToken(userID uuid.UUID) (string, error)
-	ValidateToken(tokenString string) (uuid.UUID, error)
+	GenerateToken(userID uuid.UUID, sessionID uuid.UUID, roles []string, client string) (string, error)
+	ValidateToken(tokenString string, clients ...string) (uuid.UUID, error)
+	ParseToken(tokenString string, clients ...string) (*Claims, error)
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	RevokeAllTokens(userID uuid.UUID) error
+	GenerateRefreshToken() (RefreshToken, error)
+	HashRefreshToken(refreshToken string) string
 }
 
 type JWT struct {
-	secretKey   string
-	expiredTime uint
+	secretKey          string
+	issuer             string
+	audiences          map[string]string
+	clockSkew          time.Duration
+	algorithm          string
+	hs512Until         time.Time
+	keyRotation        time.Duration
+	keys               []signingKey
+	keysReloadedAt     time.Time
+	mutex              sync.RWMutex
+	expiredTime        uint
+	refreshExpiredTime uint
+	redis              *redis.Client
 }
 
 type Claims struct {
-	ID uuid.UUID
+	ID        uuid.UUID
+	SessionID uuid.UUID
+	Version   int64
+	Roles     []string `json:"roles,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewJWT(env *env.Env) *JWT {
-	return &JWT{
-		secretKey:   env.JWTSecretKey,
-		expiredTime: env.JWTExpiredDays,
+type RefreshToken struct {
+	Token     string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+func NewJWT(env *env.Env, redis *redis.Client) *JWT {
+	JWT := JWT{
+		secretKey:          env.JWTSecretKey,
+		issuer:             env.JWTIssuer,
+		audiences:          map[string]string{ClientGame: env.JWTAudience, ClientAdmin: env.JWTAdminAudience, ClientService: env.JWTServiceAudience},
+		clockSkew:          time.Second * time.Duration(env.JWTClockSkewSeconds),
+		algorithm:          env.JWTAlgorithm,
+		keyRotation:        time.Hour * 24 * time.Duration(env.JWTKeyRotationDays),
+		expiredTime:        env.JWTExpiredMinutes,
+		refreshExpiredTime: env.JWTRefreshExpiredDays,
+		redis:              redis,
+	}
+
+	if JWT.algorithm == "" {
+		JWT.algorithm = jwt.SigningMethodHS512.Alg()
+	}
+
+	if env.JWTHS512CompatibilityUntil != "" {
+		hs512Until, err := time.Parse(time.RFC3339, env.JWTHS512CompatibilityUntil)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		JWT.hs512Until = hs512Until
+	}
+
+	if JWT.asymmetric() && time.Now().Before(JWT.hs512Until) {
+		log.Printf("HS512 access tokens are accepted until %s", JWT.hs512Until.Format(time.RFC3339))
 	}
+
+	err := JWT.Rotate()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return &JWT
 }
 
-func (j *JWT) GenerateToken(userID uuid.UUID) (string, error) {
+func (j *JWT) GenerateToken(userID uuid.UUID, sessionID uuid.UUID, roles []string, client string) (string, error) {
+	audience, ok := j.audiences[client]
+	if !ok {
+		return "", fmt.Errorf("unknown client %q", client)
+	}
+
+	version, err := j.tokenVersion(userID)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
 	claim := Claims{
-		ID: userID,
+		ID:        userID,
+		SessionID: sessionID,
+		Version:   version,
+		Roles:     roles,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    j.issuer,
+			Subject:   userID.String(),
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(
-				time.Now().Add(time.Hour * 24 * time.Duration(j.expiredTime)),
+				now.Add(time.Minute * time.Duration(j.expiredTime)),
 			),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS512, claim)
+	if !j.asymmetric() {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS512, claim)
+
+		tokenStirng, err := token.SignedString([]byte(j.secretKey))
+		if err != nil {
+			return "", err
+		}
+
+		return tokenStirng, nil
+	}
 
-	tokenStirng, err := token.SignedString([]byte(j.secretKey))
+	key, err := j.activeKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.method, claim)
+	token.Header["kid"] = key.id
+
+	tokenStirng, err := token.SignedString(key.privateKey)
 	if err != nil {
 		return "", err
 	}
@@ -50,21 +161,143 @@ func (j *JWT) GenerateToken(userID uuid.UUID) (string, error) {
 	return tokenStirng, nil
 }
 
-func (j *JWT) ValidateToken(tokenString string) (uuid.UUID, error) {
+func (j *JWT) ValidateToken(tokenString string, clients ...string) (uuid.UUID, error) {
+	claims, err := j.ParseToken(tokenString, clients...)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return claims.ID, nil
+}
+
+// ParseToken only accepts tokens whose audience belongs to one of the given clients.
+func (j *JWT) ParseToken(tokenString string, clients ...string) (*Claims, error) {
 	var claims Claims
 
 	token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
-		return []byte(j.secretKey), nil
-	})
+		if token.Method == jwt.SigningMethodHS512 {
+			return []byte(j.secretKey), nil
+		}
+
+		return j.verificationKey(token)
+	},
+		jwt.WithValidMethods(j.validMethods()),
+		jwt.WithIssuer(j.issuer),
+		jwt.WithLeeway(j.clockSkew),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
 
 	if !token.Valid {
-		return uuid.Nil, err
+		return nil, errors.New("token invalid")
 	}
 
-	userID := claims.ID
+	if !j.audienceAllowed(claims.Audience, clients) {
+		return nil, errors.New("token audience invalid")
+	}
+
+	if claims.RegisteredClaims.ID != "" {
+		revoked, err := j.redis.Exists(context.Background(), denylistKey(claims.RegisteredClaims.ID)).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		if revoked > 0 {
+			return nil, errors.New("token revoked")
+		}
+	}
+
+	version, err := j.tokenVersion(claims.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Version != version {
+		return nil, errors.New("token revoked")
+	}
+
+	return &claims, nil
+}
+
+func (j *JWT) audienceAllowed(audience jwt.ClaimStrings, clients []string) bool {
+	for _, client := range clients {
+		expected, ok := j.audiences[client]
+		if ok && slices.Contains(audience, expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// validMethods pins the configured algorithm. HS512 is only accepted next to
+// RS256 / EdDSA until JWT_HS512_COMPATIBILITY_UNTIL.
+func (j *JWT) validMethods() []string {
+	if !j.asymmetric() {
+		return []string{jwt.SigningMethodHS512.Alg()}
+	}
+
+	methods := []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+
+	if j.secretKey != "" && time.Now().Before(j.hs512Until) {
+		methods = append(methods, jwt.SigningMethodHS512.Alg())
+	}
+
+	return methods
+}
+
+func (j *JWT) RevokeToken(tokenID string, expiresAt time.Time) error {
+	expiration := time.Until(expiresAt)
+	if tokenID == "" || expiration <= 0 {
+		return nil
+	}
+
+	return j.redis.Set(context.Background(), denylistKey(tokenID), 1, expiration).Err()
+}
+
+func (j *JWT) RevokeAllTokens(userID uuid.UUID) error {
+	return j.redis.Incr(context.Background(), versionKey(userID)).Err()
+}
+
+func (j *JWT) tokenVersion(userID uuid.UUID) (int64, error) {
+	version, err := j.redis.Get(context.Background(), versionKey(userID)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+
+	return version, err
+}
+
+func denylistKey(tokenID string) string {
+	return fmt.Sprintf("token:denylist:%s", tokenID)
+}
+
+func versionKey(userID uuid.UUID) string {
+	return fmt.Sprintf("token:version:%s", userID.String())
+}
+
+func (j *JWT) GenerateRefreshToken() (RefreshToken, error) {
+	buffer := make([]byte, 32)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return RefreshToken{}, err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(buffer)
+
+	return RefreshToken{
+		Token:     token,
+		Hash:      j.HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(time.Hour * 24 * time.Duration(j.refreshExpiredTime)),
+	}, nil
+}
+
+func (j *JWT) HashRefreshToken(refreshToken string) string {
+	hash := sha256.Sum256([]byte(refreshToken))
 
-	return userID, nil
+	return hex.EncodeToString(hash[:])
 }
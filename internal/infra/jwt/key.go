@@ -0,0 +1,426 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	signingKeysKey  = "jwt:signing_keys"
+	rotationLockKey = "jwt:signing_keys:rotation"
+
+	keysReloadInterval = 10 * time.Second
+)
+
+// storedKey holds a private key sealed with JWT_SECRET_KEY. PrivateKey is the
+// plain PEM of keys stored before encryption and is only read.
+type storedKey struct {
+	EncryptedPrivateKey string    `json:"encrypted_private_key,omitempty"`
+	PrivateKey          string    `json:"private_key,omitempty"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type signingKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	createdAt  time.Time
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (j *JWT) asymmetric() bool {
+	return j.algorithm == jwt.SigningMethodRS256.Alg() || j.algorithm == jwt.SigningMethodEdDSA.Alg()
+}
+
+func (j *JWT) LoadKeys() error {
+	if !j.asymmetric() {
+		return nil
+	}
+
+	stored, err := j.redis.HGetAll(context.Background(), signingKeysKey).Result()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(stored))
+
+	for keyID, value := range stored {
+		key, encrypted, err := j.parseKey(keyID, value)
+		if err != nil {
+			log.Printf("JWT: can't load key %s: %v\n", keyID, err)
+
+			continue
+		}
+
+		if !encrypted {
+			err = j.storeKey(key)
+			if err != nil {
+				log.Printf("JWT: can't encrypt key %s: %v\n", keyID, err)
+			}
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Slice(keys, func(a, b int) bool {
+		return keys[a].createdAt.After(keys[b].createdAt)
+	})
+
+	j.mutex.Lock()
+	j.keys = keys
+	j.mutex.Unlock()
+
+	return nil
+}
+
+func (j *JWT) Rotate() error {
+	if !j.asymmetric() {
+		return nil
+	}
+
+	err := j.LoadKeys()
+	if err != nil {
+		return err
+	}
+
+	if j.rotationDue() {
+		locked, err := j.redis.SetNX(context.Background(), rotationLockKey, 1, time.Minute).Result()
+		if err != nil {
+			return err
+		}
+
+		if !locked {
+			return j.waitForKeys()
+		}
+
+		err = j.generateKey()
+
+		j.redis.Del(context.Background(), rotationLockKey)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return j.pruneKeys()
+}
+
+func (j *JWT) rotationDue() bool {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	return len(j.keys) == 0 ||
+		j.keys[0].method.Alg() != j.algorithm ||
+		(j.keyRotation > 0 && time.Since(j.keys[0].createdAt) >= j.keyRotation)
+}
+
+func (j *JWT) waitForKeys() error {
+	for range 10 {
+		time.Sleep(500 * time.Millisecond)
+
+		err := j.LoadKeys()
+		if err != nil {
+			return err
+		}
+
+		_, err = j.activeKey()
+		if err == nil {
+			return nil
+		}
+	}
+
+	return errors.New("no signing key available")
+}
+
+func (j *JWT) JWKS() JWKS {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	jwks := JWKS{
+		Keys: make([]JWK, 0, len(j.keys)),
+	}
+
+	for _, key := range j.keys {
+		jwk := JWK{
+			KeyID:     key.id,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+
+		switch publicKey := key.privateKey.Public().(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+func (j *JWT) activeKey() (signingKey, error) {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	if len(j.keys) == 0 {
+		return signingKey{}, errors.New("no signing key available")
+	}
+
+	return j.keys[0], nil
+}
+
+func (j *JWT) verificationKey(token *jwt.Token) (any, error) {
+	keyID, _ := token.Header["kid"].(string)
+
+	publicKey, found := j.findKey(keyID, token.Method.Alg())
+	if found {
+		return publicKey, nil
+	}
+
+	err := j.reloadKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, found = j.findKey(keyID, token.Method.Alg())
+	if found {
+		return publicKey, nil
+	}
+
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// reloadKeys loads the keys for an unknown key ID at most once per
+// keysReloadInterval, so forged key IDs can't flood Redis.
+func (j *JWT) reloadKeys() error {
+	j.mutex.Lock()
+
+	if time.Since(j.keysReloadedAt) < keysReloadInterval {
+		j.mutex.Unlock()
+
+		return nil
+	}
+
+	j.keysReloadedAt = time.Now()
+	j.mutex.Unlock()
+
+	return j.LoadKeys()
+}
+
+func (j *JWT) findKey(keyID string, algorithm string) (crypto.PublicKey, bool) {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	for _, key := range j.keys {
+		if key.id == keyID && key.method.Alg() == algorithm {
+			return key.privateKey.Public(), true
+		}
+	}
+
+	return nil, false
+}
+
+func (j *JWT) generateKey() error {
+	var privateKey crypto.Signer
+	var err error
+
+	switch j.algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		privateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		err = fmt.Errorf("unsupported algorithm %q", j.algorithm)
+	}
+	if err != nil {
+		return err
+	}
+
+	key := signingKey{
+		id:         uuid.New().String(),
+		privateKey: privateKey,
+		createdAt:  time.Now().UTC(),
+	}
+
+	err = j.storeKey(key)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("JWT: generated signing key %s\n", key.id)
+
+	return j.LoadKeys()
+}
+
+func (j *JWT) storeKey(key signingKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key.privateKey)
+	if err != nil {
+		return err
+	}
+
+	aead, err := j.keyCipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+
+	value, err := json.Marshal(storedKey{
+		EncryptedPrivateKey: base64.StdEncoding.EncodeToString(aead.Seal(nonce, nonce, der, []byte(key.id))),
+		CreatedAt:           key.createdAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	return j.redis.HSet(context.Background(), signingKeysKey, key.id, value).Err()
+}
+
+// keyCipher seals private keys with a key derived from JWT_SECRET_KEY, so
+// reading Redis alone is not enough to sign tokens.
+func (j *JWT) keyCipher() (cipher.AEAD, error) {
+	secret := sha256.Sum256([]byte(j.secretKey))
+
+	block, err := aes.NewCipher(secret[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (j *JWT) pruneKeys() error {
+	retention := j.keyRotation + time.Minute*time.Duration(j.expiredTime)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	keys := make([]signingKey, 0, len(j.keys))
+
+	for i, key := range j.keys {
+		if i > 0 && time.Since(j.keys[i-1].createdAt) > retention {
+			err := j.redis.HDel(context.Background(), signingKeysKey, key.id).Err()
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	j.keys = keys
+
+	return nil
+}
+
+func (j *JWT) parseKey(keyID string, value string) (signingKey, bool, error) {
+	var stored storedKey
+
+	err := json.Unmarshal([]byte(value), &stored)
+	if err != nil {
+		return signingKey{}, false, err
+	}
+
+	der, err := j.decryptKey(keyID, stored)
+	if err != nil {
+		return signingKey{}, false, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return signingKey{}, false, err
+	}
+
+	key := signingKey{
+		id:        keyID,
+		createdAt: stored.CreatedAt,
+	}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method = jwt.SigningMethodRS256
+		key.privateKey = privateKey
+	case ed25519.PrivateKey:
+		key.method = jwt.SigningMethodEdDSA
+		key.privateKey = privateKey
+	default:
+		return signingKey{}, false, errors.New("unsupported key type")
+	}
+
+	return key, stored.EncryptedPrivateKey != "", nil
+}
+
+func (j *JWT) decryptKey(keyID string, stored storedKey) ([]byte, error) {
+	if stored.EncryptedPrivateKey == "" {
+		block, _ := pem.Decode([]byte(stored.PrivateKey))
+		if block == nil {
+			return nil, errors.New("invalid pem")
+		}
+
+		return block.Bytes, nil
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(stored.EncryptedPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := j.keyCipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("invalid encrypted key")
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/estella-studio/atr-backend/internal/infra/env"
 	"github.com/google/uuid"
@@ -15,8 +16,10 @@ import (
 
 type MailerItf interface {
 	NewMail(to string, subject string, body string) error
-	AccountRegistration(to string, code uint) error
-	PasswordReset(to string, id uuid.UUID, code uint) error
+	AccountRegistration(to string, code string) error
+	PasswordReset(to string, id uuid.UUID, code string) error
+	LoginLockout(to string, lockedUntil time.Time) error
+	PasswordlessLogin(to string, code string, link string, expiresAt time.Time) error
 }
 
 type Mailer struct {
@@ -36,7 +39,7 @@ type PasswordReset struct {
 	TemplateUUID      uuid.UUID `json:"template_uuid"`
 	TemplateVariables struct {
 		UUID               uuid.UUID `json:"uuid"`
-		Code               uint      `json:"code"`
+		Code               string    `json:"code"`
 		CompanyInfoName    string    `json:"company_info_name"`
 		CompanyInfoAddress string    `json:"company_info_address"`
 		CompanyInfoCity    string    `json:"company_info_city"`
@@ -53,7 +56,7 @@ type AccountRegistration struct {
 	To                []To      `json:"to"`
 	TemplateUUID      uuid.UUID `json:"template_uuid"`
 	TemplateVariables struct {
-		Code               uint   `json:"code"`
+		Code               string `json:"code"`
 		CompanyInfoName    string `json:"company_info_name"`
 		CompanyInfoAddress string `json:"company_info_address"`
 		CompanyInfoCity    string `json:"company_info_city"`
@@ -88,7 +91,7 @@ func (m *Mailer) NewMail(to string, subject string, body string) error {
 	return err
 }
 
-func (m *Mailer) AccountRegistration(to string, code uint) error {
+func (m *Mailer) AccountRegistration(to string, code string) error {
 	url := m.Config.MailtrapURL
 	method := "POST"
 
@@ -145,7 +148,7 @@ func (m *Mailer) AccountRegistration(to string, code uint) error {
 	return err
 }
 
-func (m *Mailer) PasswordReset(to string, id uuid.UUID, code uint) error {
+func (m *Mailer) PasswordReset(to string, id uuid.UUID, code string) error {
 	url := m.Config.MailtrapURL
 	method := "POST"
 
@@ -202,3 +205,28 @@ func (m *Mailer) PasswordReset(to string, id uuid.UUID, code uint) error {
 
 	return err
 }
+
+func (m *Mailer) LoginLockout(to string, lockedUntil time.Time) error {
+	body := fmt.Sprintf(
+		"<p>We noticed too many failed sign in attempts to your %s account, so signing in has been locked until %s.</p>"+
+			"<p>If this wasn't you, we recommend changing your password once the lock ends.</p>",
+		m.Config.EmailFrom,
+		lockedUntil.Format(time.RFC1123),
+	)
+
+	return m.NewMail(to, "Sign in temporarily locked", body)
+}
+
+func (m *Mailer) PasswordlessLogin(to string, code string, link string, expiresAt time.Time) error {
+	body := fmt.Sprintf(
+		"<p>Your %s sign in code is <b>%s</b>.</p>"+
+			"<p>You can also sign in with <a href=\"%s\">this link</a>.</p>"+
+			"<p>The code and link can be used once and expire at %s. If you didn't request them, you can ignore this email.</p>",
+		m.Config.EmailFrom,
+		code,
+		link,
+		expiresAt.Format(time.RFC1123),
+	)
+
+	return m.NewMail(to, "Your sign in code", body)
+}
@@ -5,7 +5,26 @@ import (
 	"gorm.io/gorm"
 )
 
-func Migrate(db *gorm.DB) error {
+func Migrate(db *gorm.DB, encryptTwoFactorSecret func(secret string) (string, error)) error {
+	backfillEmailVerification := db.Migrator().HasTable(&entity.User{}) &&
+		!db.Migrator().HasColumn(&entity.User{}, "EmailVerifiedAt")
+
+	if db.Migrator().HasIndex(&entity.Data{}, "idx_data_slot") {
+		err := db.Migrator().DropIndex(&entity.Data{}, "idx_data_slot")
+		if err != nil {
+			return err
+		}
+
+		err = db.
+			Model(&entity.Data{}).
+			Where("slot = ?", "").
+			UpdateColumn("slot", nil).
+			Error
+		if err != nil {
+			return err
+		}
+	}
+
 	err := db.AutoMigrate(
 		entity.User{},
 		entity.UserDetail{},
@@ -15,8 +34,83 @@ func Migrate(db *gorm.DB) error {
 		entity.PasswordChange{},
 		entity.PasswordResetCode{},
 		entity.UserReporting{},
+		entity.RefreshToken{},
+		entity.Session{},
+		entity.RecoveryCode{},
+		entity.LinkedIdentity{},
+		entity.LoginCode{},
+		entity.UserRole{},
+		entity.RoleAssignment{},
+		entity.ModerationAction{},
+		entity.Appeal{},
 		entity.Data{},
+		entity.DataUsage{},
+		entity.Blob{},
 	)
+	if err != nil {
+		return err
+	}
+
+	// One-time codes used to be stored in plain text, codes sent before the
+	// switch to hashes can't be checked anymore and have to be requested again.
+	for _, model := range []any{&entity.Verification{}, &entity.PasswordResetCode{}} {
+		if !db.Migrator().HasColumn(model, "code") {
+			continue
+		}
+
+		err = db.Migrator().DropColumn(model, "code")
+		if err != nil {
+			return err
+		}
+	}
+
+	err = encryptTwoFactorSecrets(db, encryptTwoFactorSecret)
+	if err != nil {
+		return err
+	}
+
+	if backfillEmailVerification {
+		return db.
+			Model(&entity.User{}).
+			Where("email_verified_at IS NULL").
+			Where("guest = ?", false).
+			Update("email_verified_at", gorm.Expr("created_at")).
+			Error
+	}
+
+	return nil
+}
+
+// encryptTwoFactorSecrets encrypts the TOTP secrets stored in plain text
+// before they were encrypted at rest.
+func encryptTwoFactorSecrets(db *gorm.DB, encryptTwoFactorSecret func(secret string) (string, error)) error {
+	var users []entity.User
+
+	err := db.
+		Select("id, two_factor_secret").
+		Where("two_factor_secret <> ?", "").
+		Where("two_factor_secret NOT LIKE ?", "enc:%").
+		Find(&users).
+		Error
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		secret, err := encryptTwoFactorSecret(user.TwoFactorSecret)
+		if err != nil {
+			return err
+		}
+
+		err = db.
+			Model(&entity.User{}).
+			Where("id = ?", user.ID).
+			Update("two_factor_secret", secret).
+			Error
+		if err != nil {
+			return err
+		}
+	}
 
-	return err
+	return nil
 }
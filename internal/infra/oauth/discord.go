@@ -0,0 +1,72 @@
+package oauth
+
+import (
+	"errors"
+)
+
+type Discord struct {
+	clientID     string
+	clientSecret string
+}
+
+type discordUser struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Email      string `json:"email"`
+	Verified   bool   `json:"verified"`
+}
+
+func NewDiscord(clientID string, clientSecret string) *Discord {
+	return &Discord{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+func (d *Discord) AuthCodeURL(authRequest AuthRequest, redirectURL string) (string, error) {
+	return authCodeURL(
+		"https://discord.com/oauth2/authorize",
+		d.clientID,
+		redirectURL,
+		"identify email",
+		authRequest,
+	)
+}
+
+func (d *Discord) Exchange(code string, authRequest AuthRequest, redirectURL string) (Identity, error) {
+	var user discordUser
+
+	token, err := exchangeCode(
+		"https://discord.com/api/oauth2/token",
+		d.clientID,
+		d.clientSecret,
+		redirectURL,
+		code,
+		authRequest,
+	)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	err = getJSON("https://discord.com/api/users/@me", token.AccessToken, &user)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if user.ID == "" {
+		return Identity{}, errors.New("invalid user response")
+	}
+
+	name := user.GlobalName
+	if name == "" {
+		name = user.Username
+	}
+
+	return Identity{
+		Subject:       user.ID,
+		Email:         user.Email,
+		EmailVerified: user.Verified,
+		Name:          name,
+	}, nil
+}
@@ -0,0 +1,242 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/estella-studio/atr-backend/internal/infra/env"
+)
+
+type OAuthItf interface {
+	Providers() []string
+	AuthCodeURL(provider string, authRequest AuthRequest) (string, error)
+	Exchange(provider string, code string, authRequest AuthRequest) (Identity, error)
+	NewAuthRequest() (AuthRequest, error)
+}
+
+type Provider interface {
+	AuthCodeURL(authRequest AuthRequest, redirectURL string) (string, error)
+	Exchange(code string, authRequest AuthRequest, redirectURL string) (Identity, error)
+}
+
+type OAuth struct {
+	providers   map[string]Provider
+	redirectURL string
+}
+
+type AuthRequest struct {
+	State        string
+	CodeVerifier string
+	Nonce        string
+}
+
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+var client = &http.Client{
+	Timeout: time.Second * 10,
+}
+
+func NewOAuth(env *env.Env) OAuthItf {
+	providers := map[string]Provider{}
+
+	if env.OAuthGoogleClientID != "" {
+		providers["google"] = NewOIDC(
+			"https://accounts.google.com",
+			env.OAuthGoogleClientID,
+			env.OAuthGoogleClientSecret,
+		)
+	}
+
+	if env.OAuthDiscordClientID != "" {
+		providers["discord"] = NewDiscord(
+			env.OAuthDiscordClientID,
+			env.OAuthDiscordClientSecret,
+		)
+	}
+
+	if env.OAuthOIDCClientID != "" {
+		providers["oidc"] = NewOIDC(
+			env.OAuthOIDCIssuer,
+			env.OAuthOIDCClientID,
+			env.OAuthOIDCClientSecret,
+		)
+	}
+
+	return &OAuth{
+		providers:   providers,
+		redirectURL: env.OAuthRedirectURL,
+	}
+}
+
+func (o *OAuth) Providers() []string {
+	providers := make([]string, 0, len(o.providers))
+
+	for name := range o.providers {
+		providers = append(providers, name)
+	}
+
+	sort.Strings(providers)
+
+	return providers
+}
+
+func (o *OAuth) AuthCodeURL(provider string, authRequest AuthRequest) (string, error) {
+	p, ok := o.providers[provider]
+	if !ok {
+		return "", errors.New("provider not supported")
+	}
+
+	return p.AuthCodeURL(authRequest, o.redirectURL)
+}
+
+func (o *OAuth) Exchange(provider string, code string, authRequest AuthRequest) (Identity, error) {
+	p, ok := o.providers[provider]
+	if !ok {
+		return Identity{}, errors.New("provider not supported")
+	}
+
+	return p.Exchange(code, authRequest, o.redirectURL)
+}
+
+func (o *OAuth) NewAuthRequest() (AuthRequest, error) {
+	state, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	codeVerifier, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		return AuthRequest{}, err
+	}
+
+	return AuthRequest{
+		State:        state,
+		CodeVerifier: codeVerifier,
+		Nonce:        nonce,
+	}, nil
+}
+
+func (a AuthRequest) CodeChallenge() string {
+	hash := sha256.Sum256([]byte(a.CodeVerifier))
+
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func authCodeURL(endpoint string, clientID string, redirectURL string, scope string, authRequest AuthRequest) (string, error) {
+	authURL, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	query := authURL.Query()
+
+	query.Set("response_type", "code")
+	query.Set("client_id", clientID)
+	query.Set("redirect_uri", redirectURL)
+	query.Set("scope", scope)
+	query.Set("state", authRequest.State)
+	query.Set("nonce", authRequest.Nonce)
+	query.Set("code_challenge", authRequest.CodeChallenge())
+	query.Set("code_challenge_method", "S256")
+
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+func exchangeCode(endpoint string, clientID string, clientSecret string, redirectURL string, code string, authRequest AuthRequest) (tokenResponse, error) {
+	var token tokenResponse
+
+	form := url.Values{}
+
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURL)
+	form.Set("client_id", clientID)
+	form.Set("client_secret", clientSecret)
+	form.Set("code_verifier", authRequest.CodeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return token, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	err = doJSON(req, &token)
+	if err != nil {
+		return token, err
+	}
+
+	if token.AccessToken == "" {
+		return token, errors.New("invalid token response")
+	}
+
+	return token, nil
+}
+
+func getJSON(endpoint string, accessToken string, target any) error {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if accessToken != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+
+	return doJSON(req, target)
+}
+
+func doJSON(req *http.Request, target any) error {
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s responded with %d", req.URL.Host, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(target)
+}
+
+func randomString() (string, error) {
+	buffer := make([]byte, 32)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
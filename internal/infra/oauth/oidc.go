@@ -0,0 +1,259 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const jwksRefreshInterval = time.Minute
+
+type OIDC struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	discovery     *discovery
+	mutex         sync.Mutex
+	keys          map[string]any
+	keysFetchedAt time.Time
+	keyMutex      sync.Mutex
+}
+
+type discovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	KeyID string `json:"kid"`
+	Kty   string `json:"kty"`
+	Crv   string `json:"crv"`
+	N     string `json:"n"`
+	E     string `json:"e"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified any    `json:"email_verified"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+func NewOIDC(issuer string, clientID string, clientSecret string) *OIDC {
+	return &OIDC{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+	}
+}
+
+func (o *OIDC) AuthCodeURL(authRequest AuthRequest, redirectURL string) (string, error) {
+	discovery, err := o.discover()
+	if err != nil {
+		return "", err
+	}
+
+	return authCodeURL(
+		discovery.AuthorizationEndpoint,
+		o.clientID,
+		redirectURL,
+		"openid email profile",
+		authRequest,
+	)
+}
+
+func (o *OIDC) Exchange(code string, authRequest AuthRequest, redirectURL string) (Identity, error) {
+	discovery, err := o.discover()
+	if err != nil {
+		return Identity{}, err
+	}
+
+	token, err := exchangeCode(
+		discovery.TokenEndpoint,
+		o.clientID,
+		o.clientSecret,
+		redirectURL,
+		code,
+		authRequest,
+	)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if token.IDToken == "" {
+		return Identity{}, errors.New("missing id token")
+	}
+
+	var claims idTokenClaims
+
+	_, err = jwt.ParseWithClaims(
+		token.IDToken,
+		&claims,
+		func(token *jwt.Token) (any, error) {
+			return o.verificationKey(discovery.JWKSURI, token)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(o.clientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if claims.Nonce != authRequest.Nonce {
+		return Identity{}, errors.New("invalid nonce")
+	}
+
+	return Identity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified == true || claims.EmailVerified == "true",
+		Name:          claims.Name,
+	}, nil
+}
+
+func (o *OIDC) discover() (*discovery, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.discovery != nil {
+		return o.discovery, nil
+	}
+
+	var result discovery
+
+	err := getJSON(fmt.Sprintf("%s/.well-known/openid-configuration", o.issuer), "", &result)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(result.Issuer, "/") != o.issuer {
+		return nil, errors.New("issuer mismatch")
+	}
+
+	o.discovery = &result
+
+	return o.discovery, nil
+}
+
+// verificationKey returns the cached key for the kid of the token. The JWKS is
+// only fetched again for an unknown kid, at most once per jwksRefreshInterval.
+func (o *OIDC) verificationKey(jwksURI string, token *jwt.Token) (any, error) {
+	keyID, _ := token.Header["kid"].(string)
+
+	o.keyMutex.Lock()
+	defer o.keyMutex.Unlock()
+
+	key, ok := o.keys[keyID]
+	if ok {
+		return key, nil
+	}
+
+	if time.Since(o.keysFetchedAt) < jwksRefreshInterval {
+		return nil, errors.New("signing key not found")
+	}
+
+	var keySet jwks
+
+	err := getJSON(jwksURI, "", &keySet)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]any{}
+
+	for _, jwk := range keySet.Keys {
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			continue
+		}
+
+		keys[jwk.KeyID] = publicKey
+
+		// Tokens without a kid are verified with the first usable key.
+		_, ok := keys[""]
+		if !ok {
+			keys[""] = publicKey
+		}
+	}
+
+	o.keys = keys
+	o.keysFetchedAt = time.Now()
+
+	key, ok = o.keys[keyID]
+	if !ok {
+		return nil, errors.New("signing key not found")
+	}
+
+	return key, nil
+}
+
+func (key jwk) publicKey() (any, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		var curve elliptic.Curve
+
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve")
+		}
+
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := base64.RawURLEncoding.DecodeString(key.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}, nil
+	}
+
+	return nil, errors.New("unsupported key type")
+}
@@ -0,0 +1,323 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID    = "atr-client"
+	testRedirectURL = "http://localhost:8080/callback"
+)
+
+type testProvider struct {
+	server        *httptest.Server
+	issuer        string
+	jwksRequests  atomic.Int32
+	mutex         sync.Mutex
+	key           *rsa.PrivateKey
+	keyID         string
+	codeChallenge string
+	claims        func(claims jwt.MapClaims)
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	provider := &testProvider{}
+	provider.rotateKey(t, "key-1")
+
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, discovery{
+			Issuer:                provider.issuer,
+			AuthorizationEndpoint: provider.server.URL + "/authorize",
+			TokenEndpoint:         provider.server.URL + "/token",
+			JWKSURI:               provider.server.URL + "/jwks",
+		})
+	})
+
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		provider.jwksRequests.Add(1)
+
+		provider.mutex.Lock()
+		defer provider.mutex.Unlock()
+
+		writeJSON(w, jwks{Keys: []jwk{{
+			KeyID: provider.keyID,
+			Kty:   "RSA",
+			N:     base64.RawURLEncoding.EncodeToString(provider.key.N.Bytes()),
+			E:     base64.RawURLEncoding.EncodeToString(big.NewInt(int64(provider.key.E)).Bytes()),
+		}}})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		err := r.ParseForm()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		provider.mutex.Lock()
+		defer provider.mutex.Unlock()
+
+		hash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(hash[:]) != provider.codeChallenge {
+			http.Error(w, "invalid code_verifier", http.StatusBadRequest)
+			return
+		}
+
+		if r.PostForm.Get("code") != "code" || r.PostForm.Get("client_id") != testClientID {
+			http.Error(w, "invalid grant", http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":            provider.issuer,
+			"aud":            testClientID,
+			"sub":            "subject",
+			"email":          "leon@example.com",
+			"email_verified": true,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+		}
+
+		if provider.claims != nil {
+			provider.claims(claims)
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = provider.keyID
+
+		idToken, err := token.SignedString(provider.key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, tokenResponse{
+			AccessToken: "access",
+			TokenType:   "Bearer",
+			IDToken:     idToken,
+		})
+	})
+
+	provider.server = httptest.NewServer(mux)
+	provider.issuer = provider.server.URL
+
+	t.Cleanup(provider.server.Close)
+
+	return provider
+}
+
+func (p *testProvider) rotateKey(t *testing.T, keyID string) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.key = key
+	p.keyID = keyID
+}
+
+// login runs the authorization request against the provider and returns
+// the auth request the callback would be exchanged with.
+func (p *testProvider) login(t *testing.T, oidc *OIDC) AuthRequest {
+	t.Helper()
+
+	authRequest, err := (&OAuth{}).NewAuthRequest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := oidc.AuthCodeURL(authRequest, testRedirectURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := parsed.Query()
+
+	if query.Get("code_challenge_method") != "S256" {
+		t.Fatalf("code_challenge_method = %q, want S256", query.Get("code_challenge_method"))
+	}
+
+	if query.Get("nonce") != authRequest.Nonce {
+		t.Fatalf("nonce = %q, want %q", query.Get("nonce"), authRequest.Nonce)
+	}
+
+	p.mutex.Lock()
+	p.codeChallenge = query.Get("code_challenge")
+	p.mutex.Unlock()
+
+	return authRequest
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func TestOIDCExchange(t *testing.T) {
+	tests := []struct {
+		name        string
+		authRequest func(authRequest *AuthRequest)
+		claims      func(claims jwt.MapClaims)
+		wantErr     bool
+	}{
+		{
+			name: "valid",
+		},
+		{
+			name: "wrong code verifier",
+			authRequest: func(authRequest *AuthRequest) {
+				authRequest.CodeVerifier = "wrong"
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong nonce",
+			claims: func(claims jwt.MapClaims) {
+				claims["nonce"] = "wrong"
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing nonce",
+			claims: func(claims jwt.MapClaims) {
+				delete(claims, "nonce")
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong audience",
+			claims: func(claims jwt.MapClaims) {
+				claims["aud"] = "another-client"
+			},
+			wantErr: true,
+		},
+		{
+			name: "wrong issuer",
+			claims: func(claims jwt.MapClaims) {
+				claims["iss"] = "https://issuer.example.com"
+			},
+			wantErr: true,
+		},
+		{
+			name: "expired",
+			claims: func(claims jwt.MapClaims) {
+				claims["exp"] = time.Now().Add(-time.Minute).Unix()
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			provider := newTestProvider(t)
+			oidc := NewOIDC(provider.server.URL, testClientID, "secret")
+
+			authRequest := provider.login(t, oidc)
+
+			provider.claims = func(claims jwt.MapClaims) {
+				claims["nonce"] = authRequest.Nonce
+
+				if test.claims != nil {
+					test.claims(claims)
+				}
+			}
+
+			if test.authRequest != nil {
+				test.authRequest(&authRequest)
+			}
+
+			identity, err := oidc.Exchange("code", authRequest, testRedirectURL)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Exchange() = %+v, want error", identity)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+
+			if identity.Subject != "subject" || identity.Email != "leon@example.com" || !identity.EmailVerified {
+				t.Fatalf("Exchange() = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	provider := newTestProvider(t)
+	provider.issuer = "https://issuer.example.com"
+
+	oidc := NewOIDC(provider.server.URL, testClientID, "secret")
+
+	_, err := oidc.AuthCodeURL(AuthRequest{}, testRedirectURL)
+	if err == nil {
+		t.Fatal("AuthCodeURL() with a mismatched discovery issuer, want error")
+	}
+}
+
+func TestOIDCCachesKeys(t *testing.T) {
+	provider := newTestProvider(t)
+	oidc := NewOIDC(provider.server.URL, testClientID, "secret")
+
+	exchange := func() {
+		t.Helper()
+
+		authRequest := provider.login(t, oidc)
+
+		provider.claims = func(claims jwt.MapClaims) {
+			claims["nonce"] = authRequest.Nonce
+		}
+
+		_, err := oidc.Exchange("code", authRequest, testRedirectURL)
+		if err != nil {
+			t.Fatalf("Exchange() error = %v", err)
+		}
+	}
+
+	exchange()
+	exchange()
+
+	if requests := provider.jwksRequests.Load(); requests != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", requests)
+	}
+
+	provider.rotateKey(t, "key-2")
+	oidc.keysFetchedAt = time.Now().Add(-jwksRefreshInterval)
+
+	exchange()
+
+	if requests := provider.jwksRequests.Load(); requests != 2 {
+		t.Fatalf("JWKS fetched %d times after key rotation, want 2", requests)
+	}
+}
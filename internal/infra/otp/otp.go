@@ -0,0 +1,67 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+type OTPItf interface {
+	Generate(digits uint) (Code, error)
+	Verify(code string, salt string, hash string) bool
+}
+
+type OTP struct{}
+
+type Code struct {
+	Code string
+	Salt string
+	Hash string
+}
+
+func NewOTP() OTPItf {
+	return &OTP{}
+}
+
+func (o *OTP) Generate(digits uint) (Code, error) {
+	var code strings.Builder
+
+	for range digits {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return Code{}, err
+		}
+
+		code.WriteString(digit.String())
+	}
+
+	salt := make([]byte, 16)
+
+	_, err := rand.Read(salt)
+	if err != nil {
+		return Code{}, err
+	}
+
+	saltString := hex.EncodeToString(salt)
+
+	return Code{
+		Code: code.String(),
+		Salt: saltString,
+		Hash: hash(saltString, code.String()),
+	}, nil
+}
+
+func (o *OTP) Verify(code string, salt string, codeHash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hash(salt, strings.TrimSpace(code))), []byte(codeHash)) == 1
+}
+
+func hash(salt string, code string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(code))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,319 @@
+package s3
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/estella-studio/atr-backend/internal/infra/env"
+)
+
+type Local struct {
+	directory  string
+	urlPrefix  string
+	signingKey []byte
+}
+
+type Presigned struct {
+	Size            int64
+	Checksum        string
+	ContentEncoding string
+}
+
+type PresignVerifierItf interface {
+	VerifyPresigned(method string, objectKey string, query map[string]string) (Presigned, error)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func NewLocal(env *env.Env) S3Itf {
+	directory := filepath.Join(env.S3LocalDirectory, env.S3BucketName)
+
+	err := os.MkdirAll(directory, 0o755)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	signingKey := []byte(env.S3AccessKeySecret)
+	if len(signingKey) == 0 {
+		signingKey = make([]byte, 32)
+
+		_, err = rand.Read(signingKey)
+		if err != nil {
+			log.Panic(err)
+		}
+
+		log.Println("S3: S3_ACCESS_KEY_SECRET is empty, presigned urls are only valid until restart")
+	}
+
+	return &Local{
+		directory:  directory,
+		urlPrefix:  strings.TrimSuffix(env.S3BucketURLPrefix, "/"),
+		signingKey: signingKey,
+	}
+}
+
+func (l *Local) path(objectKey string) (string, error) {
+	path := filepath.Join(l.directory, filepath.FromSlash(objectKey))
+
+	if !strings.HasPrefix(path, l.directory+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key %q", objectKey)
+	}
+
+	return path, nil
+}
+
+func (l *Local) Upload(ctx context.Context, objectKey string, object []byte, contentType string, contentEncoding string) error {
+	path, err := l.path(objectKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		log.Printf("S3: %v\n", "can't upload file")
+
+		return err
+	}
+
+	temp := fmt.Sprintf("%s.%d.tmp", path, time.Now().UnixNano())
+
+	err = os.WriteFile(temp, object, 0o644)
+	if err != nil {
+		log.Printf("S3: %v\n", "can't upload file")
+
+		return err
+	}
+
+	err = os.Rename(temp, path)
+	if err != nil {
+		log.Printf("S3: %v\n", "can't upload file")
+
+		os.Remove(temp)
+	}
+
+	return err
+}
+
+func (l *Local) Download(ctx context.Context, objectKey string, byteRange string) (*Object, error) {
+	path, err := l.path(objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+
+		log.Printf("S3: %v\n", "can't download file")
+
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		log.Printf("S3: %v\n", "can't download file")
+
+		return nil, err
+	}
+
+	size := info.Size()
+	res := &Object{
+		Body:          file,
+		ContentLength: size,
+	}
+
+	if byteRange == "" {
+		return res, nil
+	}
+
+	start, end, err := ParseRange(byteRange, size)
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	_, err = file.Seek(start, io.SeekStart)
+	if err != nil {
+		file.Close()
+
+		log.Printf("S3: %v\n", "can't download file")
+
+		return nil, err
+	}
+
+	res.Body = readCloser{
+		Reader: io.LimitReader(file, end-start+1),
+		Closer: file,
+	}
+	res.ContentLength = end - start + 1
+	res.ContentRange = fmt.Sprintf("bytes %d-%d/%d", start, end, size)
+
+	return res, nil
+}
+
+func (l *Local) Head(ctx context.Context, objectKey string) (*Object, error) {
+	path, err := l.path(objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+
+		log.Printf("S3: %v\n", "can't head file")
+
+		return nil, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		log.Printf("S3: %v\n", "can't head file")
+
+		return nil, err
+	}
+
+	return &Object{
+		ContentLength: size,
+		Checksum:      hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func (l *Local) Delete(ctx context.Context, objectKey string) error {
+	path, err := l.path(objectKey)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Printf("S3: %v\n", "can't delete file")
+
+		return err
+	}
+
+	return nil
+}
+
+func (l *Local) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	path, err := l.path(sourceKey)
+	if err != nil {
+		return err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Printf("S3: %v\n", "can't copy file")
+
+		return err
+	}
+
+	return l.Upload(ctx, destinationKey, content, "", "")
+}
+
+func (l *Local) PresignUpload(ctx context.Context, objectKey string, size int64, checksum string, expires time.Duration) (string, error) {
+	return l.presign(http.MethodPut, objectKey, Presigned{
+		Size:     size,
+		Checksum: checksum,
+	}, expires)
+}
+
+func (l *Local) PresignDownload(ctx context.Context, objectKey string, contentEncoding string, expires time.Duration) (string, error) {
+	return l.presign(http.MethodGet, objectKey, Presigned{
+		ContentEncoding: contentEncoding,
+	}, expires)
+}
+
+func (l *Local) presign(method string, objectKey string, presigned Presigned, expires time.Duration) (string, error) {
+	_, err := l.path(objectKey)
+	if err != nil {
+		return "", err
+	}
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(time.Now().Add(expires).Unix(), 10))
+	query.Set("size", strconv.FormatInt(presigned.Size, 10))
+	query.Set("checksum", presigned.Checksum)
+	query.Set("encoding", presigned.ContentEncoding)
+	query.Set("signature", l.sign(method, objectKey, query))
+
+	return fmt.Sprintf("%s/%s?%s", l.urlPrefix, objectKey, query.Encode()), nil
+}
+
+func (l *Local) sign(method string, objectKey string, query url.Values) string {
+	mac := hmac.New(sha256.New, l.signingKey)
+
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s\n%s",
+		method,
+		objectKey,
+		query.Get("expires"),
+		query.Get("size"),
+		query.Get("checksum"),
+		query.Get("encoding"),
+	)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *Local) VerifyPresigned(method string, objectKey string, query map[string]string) (Presigned, error) {
+	values := url.Values{}
+
+	for key, value := range query {
+		values.Set(key, value)
+	}
+
+	signature, err := hex.DecodeString(values.Get("signature"))
+	if err != nil {
+		return Presigned{}, ErrInvalidSignature
+	}
+
+	expected, _ := hex.DecodeString(l.sign(method, objectKey, values))
+	if !hmac.Equal(signature, expected) {
+		return Presigned{}, ErrInvalidSignature
+	}
+
+	expires, err := strconv.ParseInt(values.Get("expires"), 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return Presigned{}, ErrInvalidSignature
+	}
+
+	size, err := strconv.ParseInt(values.Get("size"), 10, 64)
+	if err != nil {
+		return Presigned{}, ErrInvalidSignature
+	}
+
+	return Presigned{
+		Size:            size,
+		Checksum:        values.Get("checksum"),
+		ContentEncoding: values.Get("encoding"),
+	}, nil
+}
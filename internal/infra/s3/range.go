@@ -0,0 +1,50 @@
+package s3
+
+import (
+	"strconv"
+	"strings"
+)
+
+func ParseRange(byteRange string, size int64) (int64, int64, error) {
+	spec, found := strings.CutPrefix(byteRange, "bytes=")
+	if !found {
+		return 0, 0, ErrInvalidRange
+	}
+
+	first, last, found := strings.Cut(spec, "-")
+	if !found || size == 0 {
+		return 0, 0, ErrInvalidRange
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, ErrInvalidRange
+		}
+
+		if suffix > size {
+			suffix = size
+		}
+
+		return size - suffix, size - 1, nil
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, ErrInvalidRange
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, ErrInvalidRange
+		}
+
+		if end > size-1 {
+			end = size - 1
+		}
+	}
+
+	return start, end, nil
+}
@@ -3,41 +3,86 @@ package s3
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 	"github.com/estella-studio/atr-backend/internal/infra/env"
 )
 
+var (
+	ErrObjectNotFound   = errors.New("object not found")
+	ErrInvalidRange     = errors.New("invalid range")
+	ErrInvalidSignature = errors.New("invalid signature")
+)
+
 type S3Itf interface {
-	Upload(ctx context.Context, objectKey string, object []byte) error
+	Upload(ctx context.Context, objectKey string, object []byte, contentType string, contentEncoding string) error
+	Download(ctx context.Context, objectKey string, byteRange string) (*Object, error)
+	Head(ctx context.Context, objectKey string) (*Object, error)
+	Delete(ctx context.Context, objectKey string) error
+	Copy(ctx context.Context, sourceKey string, destinationKey string) error
+	PresignUpload(ctx context.Context, objectKey string, size int64, checksum string, expires time.Duration) (string, error)
+	PresignDownload(ctx context.Context, objectKey string, contentEncoding string, expires time.Duration) (string, error)
+}
+
+type Object struct {
+	Body          io.ReadCloser
+	ContentLength int64
+	ContentType   string
+	ContentRange  string
+	Checksum      string
 }
 
 type S3 struct {
 	Client          *s3.Client
+	PresignClient   *s3.PresignClient
 	bucketName      string
 	accountID       string
 	accessKeyID     string
 	accessKeySecret string
+	endpoint        string
+	region          string
+	usePathStyle    bool
 }
 
 func NewS3(env *env.Env) S3Itf {
+	if env.S3Driver == "local" {
+		return NewLocal(env)
+	}
+
 	S3 := S3{
 		bucketName:      env.S3BucketName,
 		accountID:       env.S3AccountID,
 		accessKeyID:     env.S3AccessKeyID,
 		accessKeySecret: env.S3AccessKeySecret,
+		endpoint:        env.S3Endpoint,
+		region:          env.S3Region,
+		usePathStyle:    env.S3UsePathStyle,
+	}
+
+	if env.S3Driver == "" || env.S3Driver == "r2" {
+		S3.endpoint = fmt.Sprintf("https://%s.r2.cloudflarestorage.com", env.S3AccountID)
+	}
+
+	if S3.region == "" {
+		S3.region = "auto"
 	}
 
 	client := New(&S3)
 
 	S3.Client = client
+	S3.PresignClient = s3.NewPresignClient(client)
 
 	return &S3
 }
@@ -45,24 +90,35 @@ func NewS3(env *env.Env) S3Itf {
 func New(s *S3) *s3.Client {
 	config, err := config.LoadDefaultConfig(context.Background(),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(s.accessKeyID, s.accessKeySecret, "")),
-		config.WithRegion("auto"))
+		config.WithRegion(s.region))
 	if err != nil {
 		log.Panic(err)
 	}
 
 	client := s3.NewFromConfig(config, func(o *s3.Options) {
-		o.BaseEndpoint = aws.String(fmt.Sprintf("https://%s.r2.cloudflarestorage.com", s.accountID))
+		if s.endpoint != "" {
+			o.BaseEndpoint = aws.String(s.endpoint)
+		}
+
+		o.UsePathStyle = s.usePathStyle
 	})
 
 	return client
 }
 
-func (s *S3) Upload(ctx context.Context, objectKey string, object []byte) error {
-	_, err := s.Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.bucketName),
-		Key:    aws.String(objectKey),
-		Body:   bytes.NewReader(object),
-	})
+func (s *S3) Upload(ctx context.Context, objectKey string, object []byte, contentType string, contentEncoding string) error {
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucketName),
+		Key:         aws.String(objectKey),
+		Body:        bytes.NewReader(object),
+		ContentType: aws.String(contentType),
+	}
+
+	if contentEncoding != "" {
+		input.ContentEncoding = aws.String(contentEncoding)
+	}
+
+	_, err := s.Client.PutObject(ctx, input)
 	if err != nil {
 		var apiErr smithy.APIError
 		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "EntityTooLarge" {
@@ -74,3 +130,136 @@ func (s *S3) Upload(ctx context.Context, objectKey string, object []byte) error
 
 	return err
 }
+
+func (s *S3) Download(ctx context.Context, objectKey string, byteRange string) (*Object, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(objectKey),
+	}
+
+	if byteRange != "" {
+		input.Range = aws.String(byteRange)
+	}
+
+	output, err := s.Client.GetObject(ctx, input)
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			switch apiErr.ErrorCode() {
+			case "NoSuchKey":
+				return nil, ErrObjectNotFound
+			case "InvalidRange":
+				return nil, ErrInvalidRange
+			}
+		}
+
+		log.Printf("S3: %v\n", "can't download file")
+
+		return nil, err
+	}
+
+	return &Object{
+		Body:          output.Body,
+		ContentLength: aws.ToInt64(output.ContentLength),
+		ContentType:   aws.ToString(output.ContentType),
+		ContentRange:  aws.ToString(output.ContentRange),
+	}, nil
+}
+
+func (s *S3) Head(ctx context.Context, objectKey string) (*Object, error) {
+	output, err := s.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(s.bucketName),
+		Key:          aws.String(objectKey),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) &&
+			(apiErr.ErrorCode() == "NotFound" || apiErr.ErrorCode() == "NoSuchKey") {
+			return nil, ErrObjectNotFound
+		}
+
+		log.Printf("S3: %v\n", "can't head file")
+
+		return nil, err
+	}
+
+	checksum, err := base64.StdEncoding.DecodeString(aws.ToString(output.ChecksumSHA256))
+	if err != nil {
+		log.Printf("S3: %v\n", "invalid checksum")
+
+		checksum = nil
+	}
+
+	return &Object{
+		ContentLength: aws.ToInt64(output.ContentLength),
+		ContentType:   aws.ToString(output.ContentType),
+		Checksum:      hex.EncodeToString(checksum),
+	}, nil
+}
+
+func (s *S3) PresignUpload(ctx context.Context, objectKey string, size int64, checksum string, expires time.Duration) (string, error) {
+	sum, err := hex.DecodeString(checksum)
+	if err != nil {
+		return "", err
+	}
+
+	request, err := s.PresignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:         aws.String(s.bucketName),
+		Key:            aws.String(objectKey),
+		ContentLength:  aws.Int64(size),
+		ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(sum)),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		log.Printf("S3: %v\n", "can't presign upload")
+
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (s *S3) PresignDownload(ctx context.Context, objectKey string, contentEncoding string, expires time.Duration) (string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(objectKey),
+	}
+
+	if contentEncoding != "" {
+		input.ResponseContentEncoding = aws.String(contentEncoding)
+	}
+
+	request, err := s.PresignClient.PresignGetObject(ctx, input, s3.WithPresignExpires(expires))
+	if err != nil {
+		log.Printf("S3: %v\n", "can't presign download")
+
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (s *S3) Delete(ctx context.Context, objectKey string) error {
+	_, err := s.Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		log.Printf("S3: %v\n", "can't delete file")
+	}
+
+	return err
+}
+
+func (s *S3) Copy(ctx context.Context, sourceKey string, destinationKey string) error {
+	_, err := s.Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.bucketName),
+		CopySource: aws.String(fmt.Sprintf("%s/%s", s.bucketName, sourceKey)),
+		Key:        aws.String(destinationKey),
+	})
+	if err != nil {
+		log.Printf("S3: %v\n", "can't copy file")
+	}
+
+	return err
+}
@@ -0,0 +1,191 @@
+package totp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/estella-studio/atr-backend/internal/infra/env"
+)
+
+const (
+	digits = 6
+	period = 30
+
+	encryptedPrefix = "enc:"
+)
+
+type TOTPItf interface {
+	GenerateSecret() (string, error)
+	EncryptSecret(secret string) (string, error)
+	DecryptSecret(stored string) (string, error)
+	URI(secret string, account string) string
+	Validate(secret string, code string) (int64, bool)
+	GenerateRecoveryCodes() ([]string, error)
+	HashRecoveryCode(code string) string
+}
+
+type TOTP struct {
+	issuer            string
+	skew              int64
+	recoveryCodeCount int
+	aead              cipher.AEAD
+}
+
+func NewTOTP(env *env.Env) TOTPItf {
+	key := sha256.Sum256([]byte(env.TOTPEncryptionKey))
+
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		log.Panic(err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		log.Panic(err)
+	}
+
+	return &TOTP{
+		issuer:            env.TOTPIssuer,
+		skew:              env.TOTPSkewSteps,
+		recoveryCodeCount: env.TOTPRecoveryCodeCount,
+		aead:              aead,
+	}
+}
+
+func (t *TOTP) GenerateSecret() (string, error) {
+	buffer := make([]byte, 20)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buffer), nil
+}
+
+// EncryptSecret seals a secret with TOTP_ENCRYPTION_KEY for storage.
+func (t *TOTP) EncryptSecret(secret string) (string, error) {
+	nonce := make([]byte, t.aead.NonceSize())
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	sealed := t.aead.Seal(nonce, nonce, []byte(secret), nil)
+
+	return encryptedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret opens a secret sealed by EncryptSecret. Secrets stored before
+// encryption are returned as they are.
+func (t *TOTP) DecryptSecret(stored string) (string, error) {
+	encoded, encrypted := strings.CutPrefix(stored, encryptedPrefix)
+	if !encrypted {
+		return stored, nil
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+
+	if len(sealed) < t.aead.NonceSize() {
+		return "", errors.New("invalid encrypted secret")
+	}
+
+	secret, err := t.aead.Open(nil, sealed[:t.aead.NonceSize()], sealed[t.aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+func (t *TOTP) URI(secret string, account string) string {
+	query := url.Values{}
+
+	query.Set("secret", secret)
+	query.Set("issuer", t.issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(digits))
+	query.Set("period", fmt.Sprint(period))
+
+	return fmt.Sprintf(
+		"otpauth://totp/%s:%s?%s",
+		url.PathEscape(t.issuer),
+		url.PathEscape(account),
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+	)
+}
+
+func (t *TOTP) Validate(secret string, code string) (int64, bool) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(secret))
+	if err != nil || len(code) != digits {
+		return 0, false
+	}
+
+	step := time.Now().Unix() / period
+
+	for i := -t.skew; i <= t.skew; i++ {
+		if subtle.ConstantTimeCompare([]byte(generateCode(key, step+i)), []byte(code)) == 1 {
+			return step + i, true
+		}
+	}
+
+	return 0, false
+}
+
+func (t *TOTP) GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, t.recoveryCodeCount)
+
+	for i := range codes {
+		buffer := make([]byte, 5)
+
+		_, err := rand.Read(buffer)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(hex.EncodeToString(buffer))
+		codes[i] = fmt.Sprintf("%s-%s", code[:5], code[5:])
+	}
+
+	return codes, nil
+}
+
+func (t *TOTP) HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	hash := sha256.Sum256([]byte(code))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func generateCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", digits, value%1000000)
+}
@@ -1,13 +1,32 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/estella-studio/atr-backend/internal/infra/jwt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 func (m *Middleware) Authentication(ctx *fiber.Ctx) error {
+	return m.authenticate(ctx, jwt.ClientGame)
+}
+
+func (m *Middleware) AdminAuthentication(ctx *fiber.Ctx) error {
+	return m.authenticate(ctx, jwt.ClientAdmin)
+}
+
+// SessionAuthentication accepts tokens of every client, for routes such as
+// logout that both the game and the admin panel call.
+func (m *Middleware) SessionAuthentication(ctx *fiber.Ctx) error {
+	return m.authenticate(ctx, jwt.ClientGame, jwt.ClientAdmin)
+}
+
+func (m *Middleware) authenticate(ctx *fiber.Ctx, clients ...string) error {
 	authToken := ctx.GetReqHeaders()["Authorization"]
 
 	if len(authToken) < 1 {
@@ -20,7 +39,7 @@ func (m *Middleware) Authentication(ctx *fiber.Ctx) error {
 	bearerToken := authToken[0]
 	token := strings.Split(bearerToken, " ")
 
-	userID, err := m.jwt.ValidateToken(token[1])
+	claims, err := m.jwt.ParseToken(token[1], clients...)
 	if err != nil {
 		return fiber.NewError(
 			http.StatusUnauthorized,
@@ -28,7 +47,52 @@ func (m *Middleware) Authentication(ctx *fiber.Ctx) error {
 		)
 	}
 
-	ctx.Locals("userID", userID.String())
+	if claims.SessionID != uuid.Nil {
+		session := entity.Session{
+			ID: claims.SessionID,
+		}
+
+		err = m.userRepo.GetSession(&session)
+		if err != nil || session.Revoked || session.UserID != claims.ID {
+			return fiber.NewError(
+				http.StatusUnauthorized,
+				"session invalid",
+			)
+		}
+	}
+
+	user := entity.User{
+		ID: claims.ID,
+	}
+
+	err = m.userRepo.GetAccountStatus(&user)
+	if err != nil {
+		return fiber.NewError(
+			http.StatusUnauthorized,
+			"user unauthorized",
+		)
+	}
+
+	if user.AccountLocked() {
+		ctx.Set(fiber.HeaderCacheControl, "private, no-store")
+
+		return ctx.Status(http.StatusForbidden).JSON(fiber.Map{
+			"message": fmt.Sprintf("account %s", user.Status),
+			"payload": user.ParseToDTOResponseAccountStatus(),
+		})
+	}
+
+	ctx.Locals("userID", claims.ID.String())
+	ctx.Locals("sessionID", claims.SessionID.String())
+	ctx.Locals("roles", claims.Roles)
+	ctx.Locals("emailPending", user.EmailPending())
+	ctx.Locals("tokenID", claims.RegisteredClaims.ID)
+
+	if claims.ExpiresAt != nil {
+		ctx.Locals("tokenExpiresAt", claims.ExpiresAt.Time)
+	} else {
+		ctx.Locals("tokenExpiresAt", time.Time{})
+	}
 
 	return ctx.Next()
 }
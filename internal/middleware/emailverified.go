@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// EmailVerified rejects accounts pending email verification. Pending accounts
+// are limited to managing their account: reading their info, verifying the
+// email, changing the password, listing and revoking sessions, logging out and
+// deleting the account. Every other authenticated route uses this middleware.
+func (m *Middleware) EmailVerified(ctx *fiber.Ctx) error {
+	emailPending, _ := ctx.Locals("emailPending").(bool)
+
+	if emailPending {
+		return fiber.NewError(
+			http.StatusForbidden,
+			"email not verified",
+		)
+	}
+
+	return ctx.Next()
+}
@@ -8,15 +8,19 @@ import (
 
 type MiddlewareItf interface {
 	Authentication(ctx *fiber.Ctx) error
+	AdminAuthentication(ctx *fiber.Ctx) error
+	SessionAuthentication(ctx *fiber.Ctx) error
 	UserStatus(ctx *fiber.Ctx) error
+	EmailVerified(ctx *fiber.Ctx) error
+	RequireRole(roles ...string) fiber.Handler
 }
 
 type Middleware struct {
-	jwt      jwt.JWT
+	jwt      *jwt.JWT
 	userRepo repository.UserMySQLItf
 }
 
-func NewMiddleware(jwt jwt.JWT, userRepo repository.UserMySQLItf) MiddlewareItf {
+func NewMiddleware(jwt *jwt.JWT, userRepo repository.UserMySQLItf) MiddlewareItf {
 	return &Middleware{
 		jwt:      jwt,
 		userRepo: userRepo,
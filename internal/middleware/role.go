@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/estella-studio/atr-backend/internal/domain/entity"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func (m *Middleware) RequireRole(roles ...string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		userID, err := uuid.Parse(ctx.Locals("userID").(string))
+		if err != nil {
+			return fiber.NewError(
+				http.StatusUnauthorized,
+				"user unauthorized",
+			)
+		}
+
+		userRoles := new([]entity.UserRole)
+
+		err = m.userRepo.GetRoles(userRoles, userID)
+		if err != nil {
+			return fiber.NewError(
+				http.StatusInternalServerError,
+				"failed to retrieve user roles",
+			)
+		}
+
+		for _, userRole := range *userRoles {
+			if slices.Contains(roles, userRole.Role) {
+				return ctx.Next()
+			}
+		}
+
+		return fiber.NewError(
+			http.StatusForbidden,
+			"insufficient role",
+		)
+	}
+}
@@ -18,5 +18,13 @@ func (m *Middleware) UserStatus(ctx *fiber.Ctx) error {
 		log.Println(err)
 	}
 
+	sessionID, err := uuid.Parse(ctx.Locals("sessionID").(string))
+	if err == nil && sessionID != uuid.Nil {
+		err = m.userRepo.UpdateSessionLastSeen(sessionID)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
 	return ctx.Next()
 }
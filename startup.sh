@@ -8,9 +8,12 @@ printf "LIMITER_MAX=%s\n" $LIMITER_MAX >>.env
 printf "LIMITER_EXPIRATION_MINUTES=%s\n" $LIMITER_EXPIRATION_MINUTES >>.env
 printf "BODY_LIMIT_MB=%s\n" $BODY_LIMIT_MB >>.env
 printf "ACCOUNT_REGISTRATION_CODE_DIGIT_COUNT=%s\n" $ACCOUNT_REGISTRATION_CODE_DIGIT_COUNT >>.env
+printf "ACCOUNT_REGISTRATION_EXPIRY_MINUTES=%s\n" $ACCOUNT_REGISTRATION_EXPIRY_MINUTES >>.env
+printf "ACCOUNT_REGISTRATION_CODE_RETRY_SECONDS=%s\n" $ACCOUNT_REGISTRATION_CODE_RETRY_SECONDS >>.env
 printf "PASSWORD_CHANGE_CODE_DIGIT_COUNT=%s\n" $PASSWORD_CHANGE_CODE_DIGIT_COUNT >>.env
 printf "PASSWORD_CHANGE_EXPIRY_MINUTES=%s\n" $PASSWORD_CHANGE_EXPIRY_MINUTES >>.env
 printf "PASSWORD_CHANGE_CODE_RETRY_SECONDS=%s\n" $PASSWORD_CHANGE_CODE_RETRY_SECONDS >>.env
+printf "CODE_MAX_ATTEMPTS=%s\n" $CODE_MAX_ATTEMPTS >>.env
 
 printf "APP_PORT=%s\n" $APP_PORT >>.env
 
@@ -27,14 +30,68 @@ printf "REDIS_PASSWORD=%s\n" $REDIS_PASSWORD >>.env
 printf "REDIS_DATABASE=%s\n" $REDIS_DATABASE >>.env
 printf "REDIS_EXPIRATION=%s\n" $REDIS_EXPIRATION >>.env
 
+printf "S3_DRIVER=%s\n" $S3_DRIVER >>.env
 printf "S3_BUCKET_NAME=%s\n" $S3_BUCKET_NAME >>.env
 printf "S3_ACCOUNT_ID=%s\n" $S3_ACCOUNT_ID >>.env
 printf "S3_ACCESS_KEY_ID=%s\n" $S3_ACCESS_KEY_ID >>.env
 printf "S3_ACCESS_KEY_SECRET=%s\n" $S3_ACCESS_KEY_SECRET >>.env
+printf "S3_ENDPOINT=%s\n" $S3_ENDPOINT >>.env
+printf "S3_REGION=%s\n" $S3_REGION >>.env
+printf "S3_USE_PATH_STYLE=%s\n" $S3_USE_PATH_STYLE >>.env
+printf "S3_LOCAL_DIRECTORY=%s\n" $S3_LOCAL_DIRECTORY >>.env
 printf "S3_BUCKET_URL_PREFIX=%s\n" $S3_BUCKET_URL_PREFIX >>.env
+printf "S3_PRESIGN_EXPIRY_MINUTES=%s\n" $S3_PRESIGN_EXPIRY_MINUTES >>.env
+printf "S3_UPLOAD_RETRY_COUNT=%s\n" $S3_UPLOAD_RETRY_COUNT >>.env
+printf "S3_UPLOAD_RETRY_BACKOFF_MILLISECONDS=%s\n" $S3_UPLOAD_RETRY_BACKOFF_MILLISECONDS >>.env
+
+printf "DATA_PENDING_EXPIRY_MINUTES=%s\n" $DATA_PENDING_EXPIRY_MINUTES >>.env
+printf "DATA_QUOTA_MB=%s\n" $DATA_QUOTA_MB >>.env
+printf "DATA_QUOTA_COUNT=%s\n" $DATA_QUOTA_COUNT >>.env
+printf "DATA_RECONCILE_INTERVAL_MINUTES=%s\n" $DATA_RECONCILE_INTERVAL_MINUTES >>.env
+
+printf "ADMIN_USERNAME=%s\n" $ADMIN_USERNAME >>.env
+
+printf "LOGIN_MAX_ATTEMPTS=%s\n" $LOGIN_MAX_ATTEMPTS >>.env
+printf "LOGIN_IP_MAX_ATTEMPTS=%s\n" $LOGIN_IP_MAX_ATTEMPTS >>.env
+printf "LOGIN_ATTEMPT_WINDOW_MINUTES=%s\n" $LOGIN_ATTEMPT_WINDOW_MINUTES >>.env
+printf "LOGIN_LOCKOUT_MINUTES=%s\n" $LOGIN_LOCKOUT_MINUTES >>.env
+printf "LOGIN_DELAY_MILLISECONDS=%s\n" $LOGIN_DELAY_MILLISECONDS >>.env
+
+printf "TOTP_ISSUER=%s\n" "$TOTP_ISSUER" >>.env
+printf "TOTP_ENCRYPTION_KEY=%s\n" $TOTP_ENCRYPTION_KEY >>.env
+printf "TOTP_SKEW_STEPS=%s\n" $TOTP_SKEW_STEPS >>.env
+printf "TOTP_RECOVERY_CODE_COUNT=%s\n" $TOTP_RECOVERY_CODE_COUNT >>.env
+printf "TOTP_CHALLENGE_EXPIRY_MINUTES=%s\n" $TOTP_CHALLENGE_EXPIRY_MINUTES >>.env
+printf "TOTP_CHALLENGE_MAX_ATTEMPTS=%s\n" $TOTP_CHALLENGE_MAX_ATTEMPTS >>.env
+
+printf "OAUTH_REDIRECT_URL=%s\n" $OAUTH_REDIRECT_URL >>.env
+printf "OAUTH_STATE_EXPIRY_MINUTES=%s\n" $OAUTH_STATE_EXPIRY_MINUTES >>.env
+printf "OAUTH_GOOGLE_CLIENT_ID=%s\n" $OAUTH_GOOGLE_CLIENT_ID >>.env
+printf "OAUTH_GOOGLE_CLIENT_SECRET=%s\n" $OAUTH_GOOGLE_CLIENT_SECRET >>.env
+printf "OAUTH_DISCORD_CLIENT_ID=%s\n" $OAUTH_DISCORD_CLIENT_ID >>.env
+printf "OAUTH_DISCORD_CLIENT_SECRET=%s\n" $OAUTH_DISCORD_CLIENT_SECRET >>.env
+printf "OAUTH_OIDC_ISSUER=%s\n" $OAUTH_OIDC_ISSUER >>.env
+printf "OAUTH_OIDC_CLIENT_ID=%s\n" $OAUTH_OIDC_CLIENT_ID >>.env
+printf "OAUTH_OIDC_CLIENT_SECRET=%s\n" $OAUTH_OIDC_CLIENT_SECRET >>.env
+
+printf "PASSWORDLESS_CODE_DIGIT_COUNT=%s\n" $PASSWORDLESS_CODE_DIGIT_COUNT >>.env
+printf "PASSWORDLESS_EXPIRY_MINUTES=%s\n" $PASSWORDLESS_EXPIRY_MINUTES >>.env
+printf "PASSWORDLESS_RETRY_SECONDS=%s\n" $PASSWORDLESS_RETRY_SECONDS >>.env
+printf "PASSWORDLESS_MAX_ATTEMPTS=%s\n" $PASSWORDLESS_MAX_ATTEMPTS >>.env
+printf "PASSWORDLESS_LINK_URL=%s\n" $PASSWORDLESS_LINK_URL >>.env
 
 printf "JWT_SECRET_KEY=%s\n" $JWT_SECRET_KEY >>.env
+printf "JWT_ISSUER=%s\n" $JWT_ISSUER >>.env
+printf "JWT_AUDIENCE=%s\n" $JWT_AUDIENCE >>.env
+printf "JWT_ADMIN_AUDIENCE=%s\n" $JWT_ADMIN_AUDIENCE >>.env
+printf "JWT_SERVICE_AUDIENCE=%s\n" $JWT_SERVICE_AUDIENCE >>.env
+printf "JWT_CLOCK_SKEW_SECONDS=%s\n" $JWT_CLOCK_SKEW_SECONDS >>.env
+printf "JWT_ALGORITHM=%s\n" $JWT_ALGORITHM >>.env
+printf "JWT_HS512_COMPATIBILITY_UNTIL=%s\n" $JWT_HS512_COMPATIBILITY_UNTIL >>.env
+printf "JWT_KEY_ROTATION_DAYS=%s\n" $JWT_KEY_ROTATION_DAYS >>.env
+printf "JWT_EXPIRED_MINUTES=%s\n" $JWT_EXPIRED_MINUTES >>.env
 printf "JWT_EXPIRED_DAYS=%s\n" $JWT_EXPIRED_DAYS >>.env
+printf "JWT_REFRESH_EXPIRED_DAYS=%s\n" $JWT_REFRESH_EXPIRED_DAYS >>.env
 
 printf "EMAIL_FROM=%s\n" $EMAIL_FROM >>.env
 